@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/scraper"
+	"rent-watcher/internal/storage"
+	"text/tabwriter"
+)
+
+const usage = `usage:
+  rent-watcher                                    run every configured scraper
+  rent-watcher sources arantes options [-refresh] list the Arantes search filter values
+  rent-watcher properties [-filter expr]          list stored properties matching an attribute filter`
+
+// runCommand runs the subcommand named by args, if any. It reports false when
+// there is none so the scrapers run as usual.
+func runCommand(ctx context.Context, cfg *config.Config, store storage.Storage, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch {
+	case len(args) >= 3 && args[0] == "sources" && args[1] == "arantes" && args[2] == "options":
+		return true, arantesOptions(ctx, cfg, store, args[3:])
+	case args[0] == "properties":
+		return true, listProperties(store, args[1:])
+	}
+	return true, fmt.Errorf("unknown command %q\n%s", args, usage)
+}
+
+func arantesOptions(ctx context.Context, cfg *config.Config, store storage.Storage, args []string) error {
+	flags := flag.NewFlagSet("options", flag.ContinueOnError)
+	refresh := flags.Bool("refresh", false, "read the search form even if the cached options are recent")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	arantes := scraper.NewArantesScraper(scraper.ArantesConfig(cfg.ArantesConfig), 0, 0, store, nil, nil)
+	options, err := arantes.Options(ctx, *refresh)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tVALUE\tNAME")
+	for _, option := range options {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", option.Field, option.Value, option.Label)
+	}
+	return w.Flush()
+}
+
+func listProperties(store storage.Storage, args []string) error {
+	flags := flag.NewFlagSet("properties", flag.ContinueOnError)
+	expr := flags.String("filter", "", `attribute filter, e.g. "attr.aceita_pets = true and attr.suites >= 1"`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filters, err := storage.ParseAttributeFilter(*expr)
+	if err != nil {
+		return err
+	}
+	properties, err := store.FindProperties(filters...)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPRICE\tTOTAL\tBAIRRO\tURL")
+	for _, property := range properties {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", property.ID, property.Price, property.TotalPrice, property.Bairro, property.URL)
+	}
+	return w.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"rent-watcher/internal/config"
@@ -12,6 +13,7 @@ import (
 	"rent-watcher/internal/geolocation"
 	"rent-watcher/internal/scraper"
 	"rent-watcher/internal/storage"
+	"rent-watcher/internal/transport"
 	"syscall"
 	"time"
 )
@@ -45,7 +47,14 @@ func main() {
 
 	store := storage.NewSQLStorage(db)
 
-	discordBot, err := discord.New(cfg.DiscordToken, cfg.DiscordChannel)
+	if handled, err := runCommand(ctx, cfg, store, os.Args[1:]); handled {
+		if err != nil {
+			log.Fatalf("Command failed: %v", err)
+		}
+		return
+	}
+
+	discordBot, err := discord.New(cfg.DiscordToken, cfg.DiscordChannel, cfg.DiscordAdminChannel)
 	if err != nil {
 		log.Fatalf("Failed to initialize Discord bot: %v", err)
 	}
@@ -56,28 +65,132 @@ func main() {
 	}()
 
 	geoProvider := geolocation.NewGoogleMapsClient(cfg.GoogleMapsAPIKey)
+	geoProvider.HTTPClient.Transport = transport.NewRotation(http.DefaultTransport, cfg.GeolocationRotation)
+
+	scrapers := []namedScraper{
+		{
+			name: "Arantes",
+			scraper: scraper.NewArantesScraper(
+				scraper.ArantesConfig(cfg.ArantesConfig),
+				cfg.DestinationLat,
+				cfg.DestinationLng,
+				store,
+				discordBot,
+				geoProvider,
+			),
+		},
+	}
+
+	for _, selectorConfig := range cfg.SelectorScrapers {
+		selectorScraper, err := scraper.NewSelectorScraper(
+			scraper.SelectorConfig(selectorConfig),
+			cfg.DestinationLat,
+			cfg.DestinationLng,
+			store,
+			discordBot,
+			geoProvider,
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize selector scraper: %v", err)
+		}
+		scrapers = append(scrapers, namedScraper{name: selectorConfig.Name, scraper: selectorScraper})
+	}
 
-	arantesScraper := scraper.NewArantesScraper(
-		scraper.ArantesConfig(cfg.ArantesConfig),
-		cfg.DestinationLat,
-		cfg.DestinationLng,
-		store,
-		discordBot,
-		geoProvider,
-	)
+	for _, externalConfig := range cfg.ExternalScrapers {
+		externalScraper, err := scraper.NewExternalScraper(
+			scraper.ExternalConfig(externalConfig),
+			cfg.DestinationLat,
+			cfg.DestinationLng,
+			store,
+			discordBot,
+			geoProvider,
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize external scraper: %v", err)
+		}
+		scrapers = append(scrapers, namedScraper{name: externalConfig.Name, scraper: externalScraper})
+	}
+
+	for _, zapConfig := range cfg.ZapSources {
+		zapScraper, err := scraper.NewZapScraper(
+			scraper.ZapConfig(zapConfig),
+			cfg.DestinationLat,
+			cfg.DestinationLng,
+			store,
+			discordBot,
+			geoProvider,
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize ZAP scraper: %v", err)
+		}
+		scrapers = append(scrapers, namedScraper{name: zapConfig.Name, scraper: zapScraper})
+	}
+
+	for _, olxConfig := range cfg.OLXSources {
+		olxScraper, err := scraper.NewOLXScraper(
+			scraper.OLXConfig(olxConfig),
+			cfg.DestinationLat,
+			cfg.DestinationLng,
+			store,
+			discordBot,
+			geoProvider,
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize OLX scraper: %v", err)
+		}
+		scrapers = append(scrapers, namedScraper{name: olxConfig.Name, scraper: olxScraper})
+	}
+
+	for _, quintoAndarConfig := range cfg.QuintoAndar {
+		quintoAndarScraper, err := scraper.NewQuintoAndarScraper(
+			scraper.QuintoAndarConfig(quintoAndarConfig),
+			cfg.DestinationLat,
+			cfg.DestinationLng,
+			store,
+			discordBot,
+			geoProvider,
+		)
+		if err != nil {
+			log.Fatalf("Failed to initialize QuintoAndar scraper: %v", err)
+		}
+		scrapers = append(scrapers, namedScraper{name: quintoAndarConfig.Name, scraper: quintoAndarScraper})
+	}
+
+	if len(cfg.TrackedURLs.URLs) > 0 {
+		scrapers = append(scrapers, namedScraper{
+			name: "Tracked URLs",
+			scraper: scraper.NewTrackedURLScraper(
+				scraper.TrackedURLsConfig(cfg.TrackedURLs),
+				cfg.DestinationLat,
+				cfg.DestinationLng,
+				store,
+				discordBot,
+				geoProvider,
+			),
+		})
+	}
 
 	scraperCtx, scraperCancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer scraperCancel()
 
-	if err := arantesScraper.Scrape(scraperCtx); err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			log.Println("Scraper timed out")
-		} else if errors.Is(err, context.Canceled) {
-			log.Println("Scraper was cancelled")
-		} else {
-			log.Printf("Failed to scrape Arantes: %v", err)
+	for _, s := range scrapers {
+		err := s.scraper.Scrape(scraperCtx)
+		log.Printf("Run stats: %s", s.scraper.Stats())
+		if errors.Is(scraperCtx.Err(), context.DeadlineExceeded) {
+			log.Printf("Scraper %s timed out", s.name)
+			break
+		} else if scraperCtx.Err() != nil {
+			log.Printf("Scraper %s was cancelled", s.name)
+			break
+		} else if err != nil {
+			log.Printf("Failed to scrape %s: %v", s.name, err)
 		}
 	}
 
 	log.Println("Scraping completed. Shutting down...")
 }
+
+type namedScraper struct {
+	name    string
+	scraper scraper.Scraper
+}
@@ -3,13 +3,15 @@ module rent-watcher
 go 1.23.1
 
 require (
+	github.com/PuerkitoBio/goquery v1.10.0
 	github.com/bwmarrin/discordgo v0.28.1
 	github.com/gocolly/colly v1.2.0
 	github.com/mattn/go-sqlite3 v1.14.23
+	github.com/temoto/robotstxt v1.1.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/PuerkitoBio/goquery v1.10.0 // indirect
 	github.com/andybalholm/cascadia v1.3.2 // indirect
 	github.com/antchfx/htmlquery v1.3.2 // indirect
 	github.com/antchfx/xmlquery v1.4.1 // indirect
@@ -20,7 +22,6 @@ require (
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/kennygrant/sanitize v1.2.4 // indirect
 	github.com/saintfish/chardet v0.0.0-20230101081208-5e3ef4b5456d // indirect
-	github.com/temoto/robotstxt v1.1.2 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/net v0.29.0 // indirect
 	golang.org/x/sys v0.25.0 // indirect
@@ -2,55 +2,255 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net/url"
 	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	DatabaseURL      string        `json:"database_url"`
-	DiscordToken     string        `json:"discord_token"`
-	DiscordChannel   string        `json:"discord_channel"`
-	GoogleMapsAPIKey string        `json:"google_maps_api_key"`
-	DestinationLat   float64       `json:"destination_lat"`
-	DestinationLng   float64       `json:"destination_lng"`
-	ArantesConfig    ArantesConfig `json:"arantes_config"`
+	DatabaseURL    string `json:"database_url"`
+	DiscordToken   string `json:"discord_token"`
+	DiscordChannel string `json:"discord_channel"`
+	// DiscordAdminChannel receives scraper alerts; defaults to DiscordChannel.
+	DiscordAdminChannel string                  `json:"discord_admin_channel"`
+	GoogleMapsAPIKey    string                  `json:"google_maps_api_key"`
+	DestinationLat      float64                 `json:"destination_lat"`
+	DestinationLng      float64                 `json:"destination_lng"`
+	ArantesConfig       ArantesConfig           `json:"arantes_config"`
+	SelectorScrapers    []SelectorScraperConfig `json:"selector_scrapers"`
+	TrackedURLs         TrackedURLsConfig       `json:"tracked_urls"`
+	ExternalScrapers    []ExternalScraperConfig `json:"external_scrapers"`
+	ZapSources          []ZapConfig             `json:"zap_sources"`
+	OLXSources          []OLXConfig             `json:"olx_sources"`
+	QuintoAndar         []QuintoAndarConfig     `json:"quintoandar_sources"`
+	// HTTPSettings are the defaults of sources that do not configure their own.
+	HTTPSettings
+	// GeolocationRotation applies to the Google Maps client.
+	GeolocationRotation RotationConfig `json:"geolocation_rotation"`
 }
 
 type ArantesConfig struct {
-	BaseURL    string        `json:"base_url"`
-	MaxPages   int           `json:"max_pages"`
-	UserAgent  string        `json:"user_agent"`
-	BaseParams ArantesParams `json:"base_params"`
+	BaseURL           string        `json:"base_url"`
+	MaxPages          int           `json:"max_pages"`
+	NextPageSelector  string        `json:"next_page_selector"`
+	UserAgent         string        `json:"user_agent"`
+	BaseParams        ArantesParams `json:"base_params"`
+	OptionsTTL        Duration      `json:"options_ttl"`
+	Incremental       bool          `json:"incremental"`
+	KnownStreakLimit  int           `json:"known_streak_limit"`
+	FullSweepInterval Duration      `json:"full_sweep_interval"`
+	DetailsTTL        Duration      `json:"details_ttl"`
+	DetailsWorkers    int           `json:"details_workers"`
+	DetailsDelay      Duration      `json:"details_delay"`
+	ResumeWindow      Duration      `json:"resume_window"`
+	Drift             DriftConfig   `json:"drift"`
+	HTTPSettings
+}
+
+// DriftConfig tunes the layout-drift checks run after each scrape. A run
+// fails when more than MaxFailureRatio of the listings cannot be parsed or miss
+// one of RequiredFields, when page 1 has no listings, or when a full run finds
+// fewer than MinYieldRatio times the average of the last YieldHistory runs.
+type DriftConfig struct {
+	RequiredFields  []string `json:"required_fields"`
+	MaxFailureRatio float64  `json:"max_failure_ratio"`
+	MinYieldRatio   float64  `json:"min_yield_ratio"`
+	YieldHistory    int      `json:"yield_history"`
 }
 
+// ArantesParams are the search filters of the Arantes listing. Bairro,
+// CategoriaImovel and Tipo accept lists; every combination is searched.
 type ArantesParams struct {
-	Cidade           string `json:"cidade"`
-	Bairro           string `json:"bairro"`
-	CategoriaImovel  string `json:"categoria_imovel"`
-	Tipo             string `json:"tipo"`
-	PrecoMin         string `json:"precoMin"`
-	PrecoMax         string `json:"precoMax"`
-	Quartos          string `json:"quartos"`
-	Banheiros        string `json:"banheiros"`
-	TipoOperacao     string `json:"tipoOperacao"`
-	IDOnlyIntegrador string `json:"id_only_integrador"`
-	IDIntegrador     string `json:"id_integrador"`
-	OrderBy          string `json:"order_by"`
+	Cidade           string     `json:"cidade"`
+	Bairro           StringList `json:"bairro"`
+	CategoriaImovel  StringList `json:"categoria_imovel"`
+	Tipo             StringList `json:"tipo"`
+	PrecoMin         string     `json:"precoMin"`
+	PrecoMax         string     `json:"precoMax"`
+	Quartos          string     `json:"quartos"`
+	Banheiros        string     `json:"banheiros"`
+	TipoOperacao     string     `json:"tipoOperacao"`
+	IDOnlyIntegrador string     `json:"id_only_integrador"`
+	IDIntegrador     string     `json:"id_integrador"`
+	OrderBy          string     `json:"order_by"`
+}
+
+// SelectorScraperConfig describes an agency site that can be scraped with CSS
+// selectors alone: a paginated listing of cards plus an optional details page.
+type SelectorScraperConfig struct {
+	Name         string                   `json:"name"`
+	BaseURL      string                   `json:"base_url"`
+	ListingURL   string                   `json:"listing_url"`
+	UserAgent    string                   `json:"user_agent"`
+	Pagination   PaginationConfig         `json:"pagination"`
+	CardSelector string                   `json:"card_selector"`
+	Fields       map[string]FieldSelector `json:"fields"`
+	Details      DetailsConfig            `json:"details"`
+	Operation    string                   `json:"operation"`
+	Drift        DriftConfig              `json:"drift"`
+	HTTPSettings
+}
+
+// PaginationConfig controls how listing pages are visited. ListingURL may
+// contain a {page} placeholder; NextSelector follows a "next" link instead.
+type PaginationConfig struct {
+	FirstPage    int    `json:"first_page"`
+	MaxPages     int    `json:"max_pages"`
+	NextSelector string `json:"next_selector"`
+}
+
+// FieldSelector extracts a single value from an element. An empty Selector
+// reads the element itself, Attr reads an attribute instead of the text and
+// Regex keeps its first capture group (or the whole match).
+type FieldSelector struct {
+	Selector string `json:"selector"`
+	Attr     string `json:"attr"`
+	Regex    string `json:"regex"`
+}
+
+// DetailsConfig describes the details page of a listing. The page URL is
+// taken from the card's "url" field, or built from URLTemplate with {id}.
+type DetailsConfig struct {
+	URLTemplate    string                   `json:"url_template"`
+	Fields         map[string]FieldSelector `json:"fields"`
+	AttributeRows  string                   `json:"attribute_rows"`
+	AttributeLabel string                   `json:"attribute_label"`
+	AttributeValue string                   `json:"attribute_value"`
+}
+
+// TrackedURLsConfig lists individual listing pages to watch through their
+// schema.org / OpenGraph data.
+type TrackedURLsConfig struct {
+	UserAgent string      `json:"user_agent"`
+	URLs      []string    `json:"urls"`
+	Drift     DriftConfig `json:"drift"`
+	HTTPSettings
+}
+
+// ExternalScraperConfig runs a scraper written in another language. Params is
+// passed through untouched to the process on stdin.
+type ExternalScraperConfig struct {
+	Name    string            `json:"name"`
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Dir     string            `json:"dir"`
+	Env     map[string]string `json:"env"`
+	Timeout Duration          `json:"timeout"`
+	Params  json.RawMessage   `json:"params"`
+	Drift   DriftConfig       `json:"drift"`
+}
+
+// ZapConfig configures a ZAP Imóveis or VivaReal search. Query holds the
+// listing endpoint parameters (business, addressCity, priceMax, ...).
+type ZapConfig struct {
+	Name      string            `json:"name"`
+	BaseURL   string            `json:"base_url"`
+	SiteURL   string            `json:"site_url"`
+	Domain    string            `json:"domain"`
+	UserAgent string            `json:"user_agent"`
+	PageSize  int               `json:"page_size"`
+	MaxPages  int               `json:"max_pages"`
+	Query     map[string]string `json:"query"`
+	Drift     DriftConfig       `json:"drift"`
+	HTTPSettings
+}
+
+// OLXConfig configures an OLX real estate search. SearchURL is a listing page
+// URL as built by the site's own filters; pages are selected with "o". The
+// operation defaults to "sale" for /venda URLs and "rent" otherwise.
+type OLXConfig struct {
+	Name      string      `json:"name"`
+	SearchURL string      `json:"search_url"`
+	UserAgent string      `json:"user_agent"`
+	MaxPages  int         `json:"max_pages"`
+	Operation string      `json:"operation"`
+	Drift     DriftConfig `json:"drift"`
+	HTTPSettings
+}
+
+// QuintoAndarConfig configures a QuintoAndar search around a region. Slug is
+// the region slug used by the site (e.g. "teresina-pi-brasil") and the price
+// range applies to the all-inclusive monthly cost.
+type QuintoAndarConfig struct {
+	Name      string      `json:"name"`
+	BaseURL   string      `json:"base_url"`
+	SiteURL   string      `json:"site_url"`
+	ImageURL  string      `json:"image_url"`
+	UserAgent string      `json:"user_agent"`
+	Slug      string      `json:"slug"`
+	Lat       float64     `json:"lat"`
+	Lng       float64     `json:"lng"`
+	PriceMin  int         `json:"price_min"`
+	PriceMax  int         `json:"price_max"`
+	PageSize  int         `json:"page_size"`
+	MaxPages  int         `json:"max_pages"`
+	Drift     DriftConfig `json:"drift"`
+	HTTPSettings
 }
 
 type URLValues url.Values
 
+// configFiles are the files Load reads the configuration from, in order of
+// preference.
+var configFiles = []string{"config.json", "config.yaml", "config.yml"}
+
 func Load() (*Config, error) {
-	file, err := os.Open("config.json")
-	if err != nil {
-		return nil, err
+	for _, name := range configFiles {
+		data, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var config Config
+		if err := decode(name, data, &config); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		config.applyDefaults()
+		return &config, nil
 	}
-	defer file.Close()
+	return nil, fmt.Errorf("no configuration file found (%v)", configFiles)
+}
 
-	var config Config
-	if err := json.NewDecoder(file).Decode(&config); err != nil {
-		return nil, err
+// decode reads a JSON or YAML configuration. YAML is converted to JSON first
+// so both formats share the same field names and value parsing.
+func decode(name string, data []byte, config *Config) error {
+	if ext := filepath.Ext(name); ext == ".yaml" || ext == ".yml" {
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return err
+		}
+		converted, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		data = converted
 	}
+	return json.Unmarshal(data, config)
+}
 
-	return &config, nil
+// applyDefaults copies the global HTTP settings into every source that does
+// not configure its own.
+func (c *Config) applyDefaults() {
+	c.ArantesConfig.HTTPSettings = c.ArantesConfig.withDefaults(c.HTTPSettings)
+	c.TrackedURLs.HTTPSettings = c.TrackedURLs.withDefaults(c.HTTPSettings)
+	for i := range c.SelectorScrapers {
+		c.SelectorScrapers[i].HTTPSettings = c.SelectorScrapers[i].withDefaults(c.HTTPSettings)
+	}
+	for i := range c.ZapSources {
+		c.ZapSources[i].HTTPSettings = c.ZapSources[i].withDefaults(c.HTTPSettings)
+	}
+	for i := range c.OLXSources {
+		c.OLXSources[i].HTTPSettings = c.OLXSources[i].withDefaults(c.HTTPSettings)
+	}
+	for i := range c.QuintoAndar {
+		c.QuintoAndar[i].HTTPSettings = c.QuintoAndar[i].withDefaults(c.HTTPSettings)
+	}
 }
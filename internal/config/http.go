@@ -0,0 +1,119 @@
+package config
+
+// HTTPSettings groups the request settings of a source. It is embedded in
+// every source config, and in Config for the defaults of sources that leave
+// a setting out, so its fields appear directly in each JSON object.
+type HTTPSettings struct {
+	Politeness PolitenessConfig `json:"politeness"`
+	Retry      RetryConfig      `json:"retry"`
+	Rotation   RotationConfig   `json:"rotation"`
+	Cache      CacheConfig      `json:"cache"`
+	Archive    ArchiveConfig    `json:"archive"`
+	Session    SessionConfig    `json:"session"`
+}
+
+// withDefaults fills every setting left unconfigured from defaults. Sessions
+// belong to a single site and are never inherited.
+func (hs HTTPSettings) withDefaults(defaults HTTPSettings) HTTPSettings {
+	if hs.Politeness == (PolitenessConfig{}) {
+		hs.Politeness = defaults.Politeness
+	}
+	if hs.Retry.isZero() {
+		hs.Retry = defaults.Retry
+	}
+	if hs.Rotation.isZero() {
+		hs.Rotation = defaults.Rotation
+	}
+	if hs.Cache == (CacheConfig{}) {
+		hs.Cache = defaults.Cache
+	}
+	if hs.Archive == (ArchiveConfig{}) {
+		hs.Archive = defaults.Archive
+	}
+	return hs
+}
+
+// PolitenessConfig limits how hard a source's site is crawled. All limits
+// apply per host; zero values disable the corresponding limit.
+type PolitenessConfig struct {
+	Delay             Duration `json:"delay"`
+	RandomDelay       Duration `json:"random_delay"`
+	Parallelism       int      `json:"parallelism"`
+	RequestsPerMinute int      `json:"requests_per_minute"`
+	RespectRobotsTxt  bool     `json:"respect_robots_txt"`
+	// CrawlDelay overrides the Crawl-delay read from robots.txt.
+	CrawlDelay Duration `json:"crawl_delay"`
+}
+
+// RetryConfig controls how failed requests are retried. Budget is the number
+// of retries allowed per run across all requests of the source; a negative
+// budget is unlimited. MaxRetryAfter caps the waits a Retry-After asks for.
+type RetryConfig struct {
+	MaxAttempts    int      `json:"max_attempts"`
+	InitialBackoff Duration `json:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff"`
+	Budget         int      `json:"budget"`
+	Statuses       []int    `json:"statuses"`
+	MaxRetryAfter  Duration `json:"max_retry_after"`
+}
+
+func (rc RetryConfig) isZero() bool {
+	return rc.MaxAttempts == 0 && rc.InitialBackoff == 0 && rc.MaxBackoff == 0 && rc.Budget == 0 && len(rc.Statuses) == 0 && rc.MaxRetryAfter == 0
+}
+
+// RotationConfig lists user agents and HTTP or SOCKS5 proxies
+// ("socks5://host:1080") to rotate between. Mode is "request" (default) to
+// rotate on every request or "session" to keep one choice per run. A proxy is
+// benched for BenchDuration after MaxFailures consecutive failures.
+type RotationConfig struct {
+	UserAgents    []string `json:"user_agents"`
+	Proxies       []string `json:"proxies"`
+	Mode          string   `json:"mode"`
+	MaxFailures   int      `json:"max_failures"`
+	BenchDuration Duration `json:"bench_duration"`
+}
+
+func (rc RotationConfig) isZero() bool {
+	return len(rc.UserAgents) == 0 && len(rc.Proxies) == 0 && rc.Mode == "" && rc.MaxFailures == 0 && rc.BenchDuration == 0
+}
+
+// CacheConfig enables the on-disk HTTP cache when Dir is set. Responses
+// younger than MaxAge are reused without a request, except listing pages;
+// older ones are revalidated. DevReplay serves every cached response regardless of age, for
+// working on a scraper without hitting the site.
+type CacheConfig struct {
+	Dir       string   `json:"dir"`
+	MaxAge    Duration `json:"max_age"`
+	DevReplay bool     `json:"dev_replay"`
+}
+
+// ArchiveConfig records all HTTP traffic of the scrapers into Dir (Mode
+// "record"), or serves a recorded Dir instead of the network (Mode "replay").
+type ArchiveConfig struct {
+	Mode string `json:"mode"`
+	Dir  string `json:"dir"`
+}
+
+// SessionConfig keeps a source's cookies and optionally logs in with a form
+// POST. CookieFile persists the cookies across runs. The login form is posted
+// to LoginURL with the credentials under UsernameField and PasswordField
+// (default "username" and "password") plus LoginFields; values may reference
+// environment variables as $VAR or ${VAR}. A response redirecting to a URL
+// containing ExpiredURL, or whose body contains ExpiredText (by default, an
+// HTML password field), means the session expired and triggers a new login.
+type SessionConfig struct {
+	CookieFile    string            `json:"cookie_file"`
+	LoginURL      string            `json:"login_url"`
+	UsernameField string            `json:"username_field"`
+	PasswordField string            `json:"password_field"`
+	Username      string            `json:"username"`
+	Password      string            `json:"password"`
+	LoginFields   map[string]string `json:"login_fields"`
+	ExpiredURL    string            `json:"expired_url"`
+	ExpiredText   string            `json:"expired_text"`
+}
+
+// Enabled reports whether the source uses a session at all.
+func (sc SessionConfig) Enabled() bool {
+	return sc.CookieFile != "" || sc.LoginURL != ""
+}
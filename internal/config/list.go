@@ -0,0 +1,37 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is a list of strings that config.json may also give as a single
+// string, so existing single-valued settings keep working.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case string:
+		*l = StringList{value}
+	case []interface{}:
+		list := make(StringList, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("invalid list item %v", item)
+			}
+			list = append(list, s)
+		}
+		*l = list
+	case nil:
+		*l = nil
+	default:
+		return fmt.Errorf("invalid list %s", string(b))
+	}
+	return nil
+}
@@ -2,9 +2,34 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// propertyMigrations lists columns added to properties after its first release,
+// so databases created by older versions are upgraded in place.
+var propertyMigrations = []column{
+	{name: "source", definition: "TEXT"},
+	{name: "url", definition: "TEXT"},
+	{name: "latitude", definition: "REAL"},
+	{name: "longitude", definition: "REAL"},
+	{name: "photos", definition: "TEXT"},
+	{name: "iptu", definition: "TEXT"},
+	{name: "payload_hash", definition: "TEXT"},
+	{name: "details_fetched_at", definition: "TIMESTAMP"},
+	{name: "operation", definition: "TEXT"},
+	{name: "yearly_iptu", definition: "TEXT"},
+	{name: "financing", definition: "TEXT"},
+	{name: "price_per_m2", definition: "TEXT"},
+	{name: "enrichment_pending", definition: "INTEGER NOT NULL DEFAULT 0"},
+}
+
+type column struct {
+	name       string
+	definition string
+}
+
 func Init(databaseURL string) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", databaseURL)
 	if err != nil {
@@ -15,6 +40,10 @@ func Init(databaseURL string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	if err := addMissingColumns(db, "properties", propertyMigrations); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -42,7 +71,101 @@ func createTables(db *sql.DB) error {
             id TEXT PRIMARY KEY,
             json_data TEXT,
             created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS property_attributes (
+            property_id TEXT NOT NULL,
+            key TEXT NOT NULL,
+            value TEXT,
+            numeric_value REAL,
+            PRIMARY KEY (property_id, key)
+        );
+        CREATE INDEX IF NOT EXISTS idx_property_attributes_key ON property_attributes (key, value);
+        CREATE TABLE IF NOT EXISTS scrape_runs (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            source TEXT NOT NULL,
+            mode TEXT NOT NULL,
+            status TEXT NOT NULL,
+            pages_visited INTEGER DEFAULT 0,
+            properties_processed INTEGER DEFAULT 0,
+            started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            finished_at TIMESTAMP
+        );
+        CREATE INDEX IF NOT EXISTS idx_scrape_runs_source ON scrape_runs (source, mode, status, started_at);
+        CREATE TABLE IF NOT EXISTS scrape_run_failures (
+            run_id INTEGER NOT NULL REFERENCES scrape_runs(id),
+            url TEXT NOT NULL,
+            error TEXT
+        );
+        CREATE TABLE IF NOT EXISTS property_searches (
+            property_id TEXT NOT NULL,
+            search TEXT NOT NULL,
+            last_seen_at TIMESTAMP,
+            PRIMARY KEY (property_id, search)
+        );
+        CREATE TABLE IF NOT EXISTS source_options (
+            source TEXT NOT NULL,
+            field TEXT NOT NULL,
+            value TEXT NOT NULL,
+            label TEXT,
+            fetched_at TIMESTAMP,
+            PRIMARY KEY (source, field, value)
+        );
+        CREATE TABLE IF NOT EXISTS scrape_checkpoints (
+            source TEXT PRIMARY KEY,
+            run_id INTEGER,
+            mode TEXT NOT NULL,
+            search TEXT NOT NULL,
+            page INTEGER NOT NULL,
+            pending TEXT,
+            updated_at TIMESTAMP
+        );
+        CREATE TABLE IF NOT EXISTS quarantine (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            source TEXT,
+            property_id TEXT,
+            run_id INTEGER REFERENCES scrape_runs(id),
+            reasons TEXT NOT NULL,
+            raw_data TEXT,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
         )
     `)
 	return err
 }
+
+func addMissingColumns(db *sql.DB, table string, columns []column) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		if existing[c.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.name, c.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, c.name, err)
+		}
+	}
+	return nil
+}
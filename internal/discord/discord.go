@@ -12,11 +12,14 @@ import (
 )
 
 type Discord struct {
-	session *discordgo.Session
-	channel string
+	session      *discordgo.Session
+	channel      string
+	adminChannel string
 }
 
-func New(token, channel string) (notifier.Notifier, error) {
+// New connects to Discord. Alerts go to adminChannel, or to channel when
+// adminChannel is empty.
+func New(token, channel, adminChannel string) (notifier.Notifier, error) {
 	session, err := discordgo.New("Bot " + token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Discord session: %w", err)
@@ -26,17 +29,32 @@ func New(token, channel string) (notifier.Notifier, error) {
 		return nil, fmt.Errorf("failed to open Discord session: %w", err)
 	}
 
+	if adminChannel == "" {
+		adminChannel = channel
+	}
+
 	return &Discord{
-		session: session,
-		channel: channel,
+		session:      session,
+		channel:      channel,
+		adminChannel: adminChannel,
 	}, nil
 }
 
 func (d *Discord) NotifyNewProperty(p *models.Property) error {
+	propertyURL := p.URL
+	if propertyURL == "" {
+		propertyURL = fmt.Sprintf("https://www.arantesimoveis.com/detalhes/%s", p.ID)
+	}
+
+	title := "🏠 New Property Alert!"
+	if p.IsSale() {
+		title = "🏡 New Property for Sale!"
+	}
+
 	embed := &discordgo.MessageEmbed{
-		Title:       "🏠 New Property Alert!",
-		Description: fmt.Sprintf("[%s - %s, %s](https://www.arantesimoveis.com/detalhes/%s)", p.Logradouro, p.Bairro, p.Cidade, p.ID),
-		URL:         fmt.Sprintf("https://www.arantesimoveis.com/detalhes/%s", p.ID),
+		Title:       title,
+		Description: fmt.Sprintf("[%s - %s, %s](%s)", p.Logradouro, p.Bairro, p.Cidade, propertyURL),
+		URL:         propertyURL,
 		Color:       0x00bfff,
 		Fields:      createEmbedFields(p),
 		Footer: &discordgo.MessageEmbedFooter{
@@ -61,12 +79,85 @@ func (d *Discord) NotifyNewProperty(p *models.Property) error {
 	return nil
 }
 
+func (d *Discord) NotifyAlert(source, message string) error {
+	embed := &discordgo.MessageEmbed{
+		Title:       "⚠️ Scraper Alert: " + source,
+		Description: message,
+		Color:       0xff4500,
+		Timestamp:   time.Now().Format(time.RFC3339),
+	}
+
+	_, err := d.session.ChannelMessageSendEmbed(d.adminChannel, embed)
+	if err != nil {
+		return fmt.Errorf("error sending Discord alert: %w", err)
+	}
+
+	return nil
+}
+
 func createEmbedFields(p *models.Property) []*discordgo.MessageEmbedField {
+	if p.IsSale() {
+		return append(createSaleFields(p), createDetailFields(p)...)
+	}
+
 	fields := []*discordgo.MessageEmbedField{
 		{Name: "💰 Price", Value: formatCurrency(p.Price), Inline: true},
 		{Name: "🏢 Condo Fee", Value: formatCurrency(p.Condominio), Inline: true},
 		{Name: "💵 Total Price", Value: formatCurrency(p.TotalPrice), Inline: true},
-		{Name: "📍 Distance", Value: formatDistance(p.DistanceMeters), Inline: true},
+	}
+	if p.IPTU != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🧾 IPTU",
+			Value:  formatCurrency(p.IPTU),
+			Inline: true,
+		})
+	}
+	return append(fields, createDetailFields(p)...)
+}
+
+func createSaleFields(p *models.Property) []*discordgo.MessageEmbedField {
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "💰 Sale Price", Value: formatCurrency(p.Price), Inline: true},
+	}
+	if p.PricePerM2 != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "📐 Price per m²",
+			Value:  formatCurrency(p.PricePerM2),
+			Inline: true,
+		})
+	}
+	if p.Condominio != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🏢 Condo Fee",
+			Value:  formatCurrency(p.Condominio),
+			Inline: true,
+		})
+	}
+	if p.YearlyIPTU != "" {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🧾 IPTU (year)",
+			Value:  formatCurrency(p.YearlyIPTU),
+			Inline: true,
+		})
+	}
+	if p.Financing != "" {
+		financing := "No"
+		if p.Financing == "true" {
+			financing = "Yes"
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🏦 Financing",
+			Value:  financing,
+			Inline: true,
+		})
+	}
+	return fields
+}
+
+// createDetailFields lists what rentals and sales have in common.
+func createDetailFields(p *models.Property) []*discordgo.MessageEmbedField {
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "📍 Distance", Value: formatDistance(p), Inline: true},
 		{Name: "🏘️ Type", Value: p.TipoImovel, Inline: true},
 		{Name: "📏 Area", Value: fmt.Sprintf("%s m²", p.Metragem), Inline: true},
 		{Name: "🛏️ Bedrooms", Value: p.Quartos, Inline: true},
@@ -89,7 +180,11 @@ func formatCurrency(value string) string {
 	return fmt.Sprintf("R$ %s", strings.TrimSpace(strings.TrimPrefix(value, "R$")))
 }
 
-func formatDistance(meters int) string {
+func formatDistance(p *models.Property) string {
+	if p.EnrichPending {
+		return "distance unavailable"
+	}
+	meters := p.DistanceMeters
 	if meters < 1000 {
 		return fmt.Sprintf("%d m", meters)
 	}
@@ -0,0 +1,21 @@
+package discord
+
+import (
+	"rent-watcher/internal/models"
+	"testing"
+)
+
+func TestFormatDistance(t *testing.T) {
+	for _, tc := range []struct {
+		property models.Property
+		want     string
+	}{
+		{models.Property{DistanceMeters: 850}, "850 m"},
+		{models.Property{DistanceMeters: 2350}, "2.4 km"},
+		{models.Property{EnrichPending: true}, "distance unavailable"},
+	} {
+		if got := formatDistance(&tc.property); got != tc.want {
+			t.Errorf("formatDistance(%+v) = %q, want %q", tc.property, got, tc.want)
+		}
+	}
+}
@@ -44,6 +44,9 @@ func NewGoogleMapsClient(apiKey string) *GoogleMapsClient {
 
 func (c *GoogleMapsClient) CalculateDistance(ctx context.Context, property *models.Property, destLat, destLng float64) (int, error) {
 	origin := fmt.Sprintf("%s,%s,%s", property.Logradouro, property.Bairro, property.Cidade)
+	if property.Latitude != 0 || property.Longitude != 0 {
+		origin = fmt.Sprintf("%.7f,%.7f", property.Latitude, property.Longitude)
+	}
 	destination := fmt.Sprintf("%.7f,%.7f", destLat, destLng)
 
 	u, err := url.Parse(baseURL)
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// attributeAliases maps normalized labels onto canonical attribute keys.
+// The "" entry applies to every source; source-specific entries take precedence.
+var attributeAliases = map[string]map[string]string{
+	"": {
+		"suite":            "suites",
+		"suites":           "suites",
+		"quarto":           "quartos",
+		"quartos":          "quartos",
+		"dormitorio":       "quartos",
+		"dormitorios":      "quartos",
+		"banheiro":         "banheiros",
+		"banheiros":        "banheiros",
+		"garagem":          "garagens",
+		"garagens":         "garagens",
+		"vaga":             "garagens",
+		"vagas":            "garagens",
+		"vagas_de_garagem": "garagens",
+		"condominio":       "condominio",
+		"valor_condominio": "condominio",
+		"iptu":             "iptu",
+		"area":             "metragem",
+		"area_util":        "metragem",
+		"area_total":       "area_total",
+		"aceita_pet":       "aceita_pets",
+		"aceita_pets":      "aceita_pets",
+		"aceita_animais":   "aceita_pets",
+		"pets_allowed":     "aceita_pets",
+		"furnished":        "mobiliado",
+		"mobiliado":        "mobiliado",
+		"tipo":             "tipo_imovel",
+		"tipo_de_imovel":   "tipo_imovel",
+		"financiamento":    "aceita_financiamento",
+		"iptu_anual":       "iptu_anual",
+		"iptu_ano":         "iptu_anual",
+	},
+	"arantes": {
+		"tipo_do_imovel": "tipo_imovel",
+		"garagem_s":      "garagens",
+		"suite_s":        "suites",
+	},
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// NormalizeAttributeKey turns a site label such as "Suítes" or "Aceita Pets?"
+// into a canonical snake_case attribute key.
+func NormalizeAttributeKey(source, label string) string {
+	key := accentReplacer.Replace(strings.ToLower(strings.TrimSpace(label)))
+
+	var b strings.Builder
+	underscore := false
+	for _, r := range key {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			underscore = false
+			continue
+		}
+		if b.Len() > 0 && !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	key = strings.TrimSuffix(b.String(), "_")
+
+	if alias, ok := attributeAliases[source][key]; ok {
+		return alias
+	}
+	if alias, ok := attributeAliases[""][key]; ok {
+		return alias
+	}
+	return key
+}
+
+// NormalizeAttributeValue trims a scraped value and maps yes/no answers to
+// "true"/"false" so boolean attributes can be filtered consistently.
+func NormalizeAttributeValue(value string) string {
+	value = strings.TrimSpace(value)
+	switch accentReplacer.Replace(strings.ToLower(value)) {
+	case "sim", "s", "yes", "true":
+		return "true"
+	case "nao", "n", "no", "false":
+		return "false"
+	}
+	return value
+}
+
+// FoldText lower-cases s, strips accents and collapses whitespace, so names
+// such as "Jóquei  Clube" and "joquei clube" compare equal.
+func FoldText(s string) string {
+	return strings.Join(strings.Fields(accentReplacer.Replace(strings.ToLower(s))), " ")
+}
+
+// ParseNumeric parses numbers written in the Brazilian format ("1.234,56",
+// "R$ 1.500", "65 m²") as well as plain decimals.
+func ParseNumeric(value string) (float64, bool) {
+	s := strings.TrimSpace(value)
+	s = strings.TrimPrefix(s, "R$")
+	s = strings.TrimSuffix(s, "m²")
+	s = strings.TrimSuffix(s, "m2")
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if s == "" {
+		return 0, false
+	}
+
+	if strings.Contains(s, ",") {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else if i := strings.LastIndex(s, "."); i >= 0 && len(s)-i-1 == 3 {
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
@@ -1,19 +1,57 @@
 package models
 
+// Operations a listing can be offered for. Properties without one are rentals.
+const (
+	OperationRent = "rent"
+	OperationSale = "sale"
+)
+
+// Property is a scraped listing. For rentals Price is the monthly rent and
+// TotalPrice the monthly cost; for sales Price is the asking price, and
+// YearlyIPTU, Financing ("true", "false" or unknown) and PricePerM2 apply.
+// EnrichPending marks a property whose distance could not be calculated yet.
 type Property struct {
-	ID             string `json:"id"`
-	FirstPhoto     string `json:"first_foto"`
-	Price          string `json:"preco"`
-	Logradouro     string `json:"logradouro"`
-	Bairro         string `json:"bairro"`
-	Cidade         string `json:"cidade"`
-	Metragem       string `json:"metragem"`
-	Quartos        string `json:"quartos"`
-	Banheiros      string `json:"banheiros"`
-	Suites         string `json:"suites"`
-	Garagens       string `json:"garagens"`
-	TipoImovel     string `json:"tipo_imovel"`
-	DistanceMeters int    `json:"distance_meters"`
-	Condominio     string `json:"condominio"`
-	TotalPrice     string `json:"total_price"`
+	ID             string            `json:"id"`
+	FirstPhoto     string            `json:"first_foto"`
+	Price          string            `json:"preco"`
+	Logradouro     string            `json:"logradouro"`
+	Bairro         string            `json:"bairro"`
+	Cidade         string            `json:"cidade"`
+	Metragem       string            `json:"metragem"`
+	Quartos        string            `json:"quartos"`
+	Banheiros      string            `json:"banheiros"`
+	Suites         string            `json:"suites"`
+	Garagens       string            `json:"garagens"`
+	TipoImovel     string            `json:"tipo_imovel"`
+	DistanceMeters int               `json:"distance_meters"`
+	Condominio     string            `json:"condominio"`
+	IPTU           string            `json:"iptu,omitempty"`
+	TotalPrice     string            `json:"total_price"`
+	Source         string            `json:"source,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Latitude       float64           `json:"latitude,omitempty"`
+	Longitude      float64           `json:"longitude,omitempty"`
+	Photos         []string          `json:"photos,omitempty"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+	Operation      string            `json:"operacao,omitempty"`
+	YearlyIPTU     string            `json:"iptu_anual,omitempty"`
+	Financing      string            `json:"aceita_financiamento,omitempty"`
+	PricePerM2     string            `json:"preco_m2,omitempty"`
+	EnrichPending  bool              `json:"enrichment_pending,omitempty"`
+}
+
+func (p *Property) IsSale() bool {
+	return p.Operation == OperationSale
+}
+
+// SetAttribute stores a scraped label/value pair under its normalized key.
+func (p *Property) SetAttribute(source, label, value string) {
+	key := NormalizeAttributeKey(source, label)
+	if key == "" {
+		return
+	}
+	if p.Attributes == nil {
+		p.Attributes = make(map[string]string)
+	}
+	p.Attributes[key] = NormalizeAttributeValue(value)
 }
@@ -6,5 +6,7 @@ import (
 
 type Notifier interface {
 	NotifyNewProperty(property *models.Property) error
+	// NotifyAlert reports a problem with a scraper to the admins.
+	NotifyAlert(source, message string) error
 	Close() error
 }
@@ -2,6 +2,8 @@ package scraper
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gocolly/colly"
 )
@@ -23,15 +26,53 @@ type ArantesScraper struct {
 	mu     sync.Mutex
 	ctx    context.Context
 	cancel context.CancelFunc
+	pages  *pageTracker
+	mode   string
+	// operation is derived from the tipoOperacao of the search.
+	operation string
+	// search is the label of the query being paginated and matches the
+	// searches that found each listing of the run.
+	search  string
+	matches map[string][]string
+	// pending holds the encoded details jobs not finished yet and next the
+	// position saved in the checkpoint.
+	pending map[string]json.RawMessage
+	next    storage.Checkpoint
+	// listed reports whether the first page of some search had listings.
+	listed bool
+
+	details *colly.Collector
+	pool    *detailsPool
 }
 
 type ArantesConfig struct {
-	BaseURL    string
-	MaxPages   int
-	UserAgent  string
-	BaseParams config.ArantesParams
+	BaseURL           string
+	MaxPages          int
+	NextPageSelector  string
+	UserAgent         string
+	BaseParams        config.ArantesParams
+	OptionsTTL        config.Duration
+	Incremental       bool
+	KnownStreakLimit  int
+	FullSweepInterval config.Duration
+	DetailsTTL        config.Duration
+	DetailsWorkers    int
+	DetailsDelay      config.Duration
+	ResumeWindow      config.Duration
+	Drift             config.DriftConfig
+	config.HTTPSettings
 }
 
+// defaultFullSweepInterval is how often an incremental Arantes scraper still
+// walks every page, so delistings and price changes are picked up.
+const defaultFullSweepInterval = 24 * time.Hour
+
+// defaultDetailsTTL is how long the details of a property whose listing card
+// did not change are reused before its details page is fetched again.
+const defaultDetailsTTL = 7 * 24 * time.Hour
+
+const defaultDetailsWorkers = 3
+
 func NewArantesScraper(config ArantesConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) *ArantesScraper {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &ArantesScraper{
@@ -41,6 +82,7 @@ func NewArantesScraper(config ArantesConfig, destinationLat, destinationLng floa
 			GeolocationProvider: geoProvider,
 			DestinationLat:      destinationLat,
 			DestinationLng:      destinationLng,
+			listingPage:         isArantesListing,
 		},
 		Config: config,
 		ctx:    ctx,
@@ -48,92 +90,320 @@ func NewArantesScraper(config ArantesConfig, destinationLat, destinationLng floa
 	}
 }
 
-func (as *ArantesScraper) Scrape(ctx context.Context) error {
+func (as *ArantesScraper) Scrape(ctx context.Context) (err error) {
+	checkpoint := as.loadCheckpoint()
+	mode := as.runMode()
+	if checkpoint != nil {
+		mode = checkpoint.Mode
+	}
 	as.mu.Lock()
 	as.ctx, as.cancel = context.WithCancel(ctx)
+	as.mode = mode
+	as.matches = make(map[string][]string)
+	as.pending = make(map[string]json.RawMessage)
+	as.listed = false
 	as.mu.Unlock()
+	as.resetStats(as.ctx, "arantes")
+	as.updateStats(func(s *RunStats) { s.Resumed = checkpoint != nil })
+	as.beginRun("arantes", mode)
+	defer func() { as.finishRun(err) }()
+
+	params, operation, err := as.resolveParams(as.ctx)
+	if err != nil {
+		return err
+	}
+	as.operation = operation
 
 	collector, err := as.initCollector()
 	if err != nil {
 		return fmt.Errorf("failed to initialize collector: %w", err)
 	}
+	as.details, err = as.initDetailsCollector()
+	if err != nil {
+		return fmt.Errorf("failed to initialize details collector: %w", err)
+	}
+
+	as.pool = newDetailsPool(as.ctx, as.detailsWorkers(), as.fetchDetails, as.finishProperty)
 
 	as.setupCallbacks(collector)
-	return as.scrapePagination(collector)
+	searches := arantesSearches(params)
+	start, page := 0, 1
+	if checkpoint != nil {
+		start, page = as.resumeSearches(checkpoint, searches)
+	}
+	for i := start; i < len(searches); i++ {
+		if err = as.scrapePagination(collector, searches[i], page); err != nil {
+			break
+		}
+		page = 1
+	}
+	if err == nil {
+		as.saveCheckpoint("", 0)
+	}
+	as.pool.close()
+	as.saveSearchMatches()
+	if err == nil {
+		// Details still queued when the context ends are dropped.
+		err = as.ctx.Err()
+	}
+	if err != nil {
+		// Keep the checkpoint, with what is still pending, for the next run.
+		as.updateCheckpoint()
+		return err
+	}
+	as.deleteCheckpoint()
+	return as.checkDrift(as.Config.Drift)
+}
+
+// runMode picks a full sweep unless incremental scraping is enabled and the
+// last completed full sweep is more recent than FullSweepInterval.
+func (as *ArantesScraper) runMode() string {
+	if !as.Config.Incremental {
+		return storage.RunModeFull
+	}
+
+	interval := as.Config.FullSweepInterval.Duration()
+	if interval <= 0 {
+		interval = defaultFullSweepInterval
+	}
+
+	last, err := as.Storage.LastRun("arantes", storage.RunModeFull, storage.RunStatusCompleted)
+	if err != nil {
+		log.Printf("Error checking last full sweep, running a full sweep: %v\n", err)
+		return storage.RunModeFull
+	}
+	if last == nil || time.Since(last.StartedAt) >= interval {
+		return storage.RunModeFull
+	}
+	return storage.RunModeIncremental
 }
 
 func (as *ArantesScraper) initCollector() (*colly.Collector, error) {
-	c := colly.NewCollector(
-		colly.AllowURLRevisit(),
-		colly.UserAgent(as.Config.UserAgent),
-	)
-
-	err := c.Limit(&colly.LimitRule{
-		DomainGlob:  "*arantesimoveis.com*",
-		Parallelism: 3,
-		//RandomDelay: 2 * time.Second,
-	})
+	return as.newCollector(as.Config.UserAgent, as.Config.HTTPSettings)
+}
+
+// initDetailsCollector creates the collector of the details pages. It has its
+// own limits, so details_workers and details_delay do not slow the listing.
+func (as *ArantesScraper) initDetailsCollector() (*colly.Collector, error) {
+	c, err := as.newCollector(as.Config.UserAgent, as.Config.HTTPSettings)
+	if err != nil {
+		return nil, err
+	}
 
+	base, err := url.Parse(as.Config.BaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to set rate limit: %w", err)
+		return nil, fmt.Errorf("failed to parse base URL: %w", err)
+	}
+	err = c.Limit(&colly.LimitRule{
+		DomainGlob:  base.Host,
+		Parallelism: as.detailsWorkers(),
+		Delay:       as.Config.DetailsDelay.Duration(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set details rate limit: %w", err)
 	}
 
 	return c, nil
 }
 
+func (as *ArantesScraper) detailsWorkers() int {
+	if as.Config.DetailsWorkers <= 0 {
+		return defaultDetailsWorkers
+	}
+	return as.Config.DetailsWorkers
+}
+
 func (as *ArantesScraper) setupCallbacks(c *colly.Collector) {
 	c.OnHTML(".card-imovel", func(e *colly.HTMLElement) {
-		as.processPropertyCard(e, c)
+		as.processPropertyCard(e)
 	})
+	if as.Config.NextPageSelector != "" {
+		c.OnHTML(as.Config.NextPageSelector, func(_ *colly.HTMLElement) {
+			as.pages.markNext()
+		})
+	}
 	c.OnError(func(r *colly.Response, err error) {
 		log.Printf("Request URL: %s failed with response: %v\nError: %v\n",
 			r.Request.URL, r, err)
 	})
 }
 
-func (as *ArantesScraper) processPropertyCard(e *colly.HTMLElement, c *colly.Collector) {
-	property, rawData := as.extractPropertyData(e)
-	detailsURL := as.getDetailsURL(property.ID)
-
-	detailsCollector := c.Clone()
-	fmt.Printf("Visiting details page for property %s: %s\n", property.ID, detailsURL)
+// detailsJob carries a property from its listing card through the details
+// pool to ProcessProperty.
+type detailsJob struct {
+	property   *models.Property
+	rawData    string
+	hash       string
+	detailsURL string
+	fetchedAt  time.Time
+}
 
-	detailsCollector.OnHTML(".table-striped", func(e *colly.HTMLElement) {
-		as.extractDetailsData(e, property)
-	})
+func (as *ArantesScraper) processPropertyCard(e *colly.HTMLElement) {
+	as.updateStats(func(s *RunStats) { s.Cards++ })
+	property, rawData, err := as.extractPropertyData(e)
+	if err != nil {
+		log.Printf("Skipping card on %s: %v\n", e.Request.URL, err)
+		as.updateStats(func(s *RunStats) { s.ParseFailures++ })
+		as.quarantine(&models.Property{Source: "arantes"}, rawData, []string{err.Error()})
+		return
+	}
+	if property.ID == "" {
+		log.Printf("Skipping card without id on %s\n", e.Request.URL)
+		as.updateStats(func(s *RunStats) { s.MissingFields++ })
+		as.quarantine(property, rawData, []string{"missing id"})
+		return
+	}
+	if !as.pages.addID(property.ID) {
+		// Already queued on an earlier page of this search.
+		return
+	}
+	if !as.addMatch(property.ID) {
+		// Already queued by an earlier search of this run.
+		if as.mode == storage.RunModeIncremental {
+			as.pages.addKnown(true)
+		}
+		return
+	}
+	if as.mode == storage.RunModeIncremental {
+		exists, err := as.Storage.PropertyExists(property.ID)
+		if err != nil {
+			log.Printf("Error checking if property %s exists: %v\n", property.ID, err)
+		}
+		as.pages.addKnown(exists)
+	}
+	property.Source = "arantes"
+	property.Operation = as.operation
+	property.URL = as.getDetailsURL(property.ID)
+
+	job := &detailsJob{
+		property:   property,
+		rawData:    rawData,
+		hash:       payloadHash(rawData),
+		detailsURL: property.URL,
+	}
 
-	err := detailsCollector.Visit(detailsURL)
+	state, err := as.Storage.GetFetchState(property.ID)
 	if err != nil {
-		log.Printf("Error visiting details page for property %s: %v\n", property.ID, err)
+		log.Printf("Error getting fetch state for property %s: %v\n", property.ID, err)
 	}
 
-	property.TotalPrice = property.Price
-	if property.Price != "" && property.Condominio != "" {
-		price, err := strconv.ParseFloat(strings.ReplaceAll(property.Price, ",", "."), 64)
-		if err != nil {
-			log.Printf("Error parsing price: %v\n", err)
+	if !as.needsDetails(state, job.hash) {
+		err := as.fillFromStored(property)
+		if err == nil {
+			job.fetchedAt = state.DetailsFetchedAt
+			as.updateStats(func(s *RunStats) { s.DetailsSkipped++ })
+			as.queueStored(job)
 			return
 		}
-		condominio, err := strconv.ParseFloat(strings.ReplaceAll(property.Condominio, ",", "."), 64)
-		if err != nil {
-			log.Printf("Error parsing condominio: %v\n", err)
+		log.Printf("Error loading stored details for property %s: %v\n", property.ID, err)
+	}
+
+	as.queueDetails(job)
+}
+
+// finishProperty runs on the pool's result goroutine once a property's
+// details are known.
+func (as *ArantesScraper) finishProperty(job *detailsJob) {
+	property := job.property
+	defer as.donePending(property.ID)
+	if !as.hasRequiredFields(property, as.requiredFields()) {
+		return
+	}
+	if err := as.ProcessProperty(as.ctx, property, job.rawData); err != nil {
+		log.Printf("Error processing property: %v\n", err)
+		return
+	}
+
+	state := storage.FetchState{PayloadHash: job.hash, DetailsFetchedAt: job.fetchedAt}
+	if err := as.Storage.SaveFetchState(property.ID, state); err != nil {
+		log.Printf("Error saving fetch state for property %s: %v\n", property.ID, err)
+	}
+}
+
+// requiredFields returns the fields every Arantes listing must have.
+func (as *ArantesScraper) requiredFields() []string {
+	if len(as.Config.Drift.RequiredFields) > 0 {
+		return as.Config.Drift.RequiredFields
+	}
+	return []string{"id", "price"}
+}
+
+// needsDetails reports whether the details page has to be fetched: the
+// property is new, its card payload changed, or its details are older than
+// DetailsTTL.
+func (as *ArantesScraper) needsDetails(state *storage.FetchState, hash string) bool {
+	if state == nil || state.PayloadHash != hash || state.DetailsFetchedAt.IsZero() {
+		return true
+	}
+
+	ttl := as.Config.DetailsTTL.Duration()
+	if ttl <= 0 {
+		ttl = defaultDetailsTTL
+	}
+	return time.Since(state.DetailsFetchedAt) >= ttl
+}
+
+// fetchDetails visits the details page of a job's property on a pool worker.
+func (as *ArantesScraper) fetchDetails(job *detailsJob) {
+	property := job.property
+	detailsCollector := as.details.Clone()
+	log.Printf("Visiting details page for property %s: %s\n", property.ID, job.detailsURL)
+
+	detailsCollector.OnHTML(".table-striped", func(e *colly.HTMLElement) {
+		as.extractDetailsData(e, property)
+	})
+	detailsCollector.OnHTML("html", func(e *colly.HTMLElement) {
+		FillMissingFields(property, ExtractStructuredData(e.DOM))
+	})
+
+	if err := detailsCollector.Visit(job.detailsURL); err != nil {
+		if as.ctx.Err() != nil {
+			// The pool drops the job; it stays pending in the checkpoint.
 			return
 		}
-
-		property.TotalPrice = fmt.Sprintf("%.2f", price+condominio)
+		log.Printf("Error visiting details page for property %s: %v\n", property.ID, err)
+		as.recordFailure(job.detailsURL, err)
+		return
 	}
+	job.fetchedAt = time.Now()
+}
 
-	if err := as.ProcessProperty(as.ctx, property, rawData); err != nil {
-		log.Printf("Error processing property: %v\n", err)
+// fillFromStored completes a property scraped from its card with the details
+// saved on a previous run. Attributes are left nil so the stored ones are kept.
+func (as *ArantesScraper) fillFromStored(property *models.Property) error {
+	stored, err := as.Storage.GetProperty(property.ID)
+	if err != nil {
+		return err
 	}
+
+	FillMissingFields(property, stored)
+	property.Suites = getValueOrDefault(property.Suites, stored.Suites)
+	property.Garagens = getValueOrDefault(property.Garagens, stored.Garagens)
+	property.Condominio = getValueOrDefault(property.Condominio, stored.Condominio)
+	property.IPTU = getValueOrDefault(property.IPTU, stored.IPTU)
+	return nil
+}
+
+// isArantesListing reports whether u is a page of the Arantes listing, as
+// opposed to a details page.
+func isArantesListing(u *url.URL) bool {
+	return strings.HasPrefix(u.Path, "/listagem")
 }
 
-func (as *ArantesScraper) extractPropertyData(e *colly.HTMLElement) (*models.Property, string) {
+// payloadHash fingerprints a listing card's json_imovel payload.
+func payloadHash(payload string) string {
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+func (as *ArantesScraper) extractPropertyData(e *colly.HTMLElement) (*models.Property, string, error) {
 	var property models.Property
 	jsonData := e.ChildAttr("input.json_imovel", "value")
+	if jsonData == "" {
+		return nil, "", fmt.Errorf("missing json_imovel")
+	}
 	if err := json.Unmarshal([]byte(jsonData), &property); err != nil {
-		log.Printf("Error unmarshalling JSON: %v\n", err)
-		return &models.Property{}, jsonData
+		return nil, jsonData, fmt.Errorf("failed to unmarshal json_imovel: %w", err)
 	}
 
 	property.Quartos = getValueOrDefault(e.ChildText(".fa-bed + span"), property.Quartos)
@@ -146,7 +416,7 @@ func (as *ArantesScraper) extractPropertyData(e *colly.HTMLElement) (*models.Pro
 		property.FirstPhoto = as.Config.BaseURL + property.FirstPhoto
 	}
 
-	return &property, jsonData
+	return &property, jsonData, nil
 }
 
 func (as *ArantesScraper) getDetailsURL(propertyID string) string {
@@ -157,6 +427,10 @@ func (as *ArantesScraper) extractDetailsData(e *colly.HTMLElement, property *mod
 	e.ForEach("tr", func(_ int, row *colly.HTMLElement) {
 		label := strings.TrimSpace(row.ChildText("td:first-child"))
 		value := strings.TrimSpace(row.ChildText("td:last-child"))
+		if label == "" {
+			return
+		}
+		property.SetAttribute("arantes", label, value)
 
 		switch {
 		case strings.Contains(label, "Condomínio"):
@@ -182,38 +456,147 @@ func getValueOrDefault(value, defaultValue string) string {
 	return defaultValue
 }
 
-func (as *ArantesScraper) scrapePagination(c *colly.Collector) error {
-	baseParams := url.Values{
-		"cidade":             {as.Config.BaseParams.Cidade},
-		"bairro":             {as.Config.BaseParams.Bairro},
-		"categoria_imovel":   {as.Config.BaseParams.CategoriaImovel},
-		"tipo":               {as.Config.BaseParams.Tipo},
-		"precoMin":           {as.Config.BaseParams.PrecoMin},
-		"precoMax":           {as.Config.BaseParams.PrecoMax},
-		"quartos":            {as.Config.BaseParams.Quartos},
-		"banheiros":          {as.Config.BaseParams.Banheiros},
-		"tipoOperacao":       {as.Config.BaseParams.TipoOperacao},
-		"id_only_integrador": {as.Config.BaseParams.IDOnlyIntegrador},
-		"id_integrador":      {as.Config.BaseParams.IDIntegrador},
-		"order_by":           {as.Config.BaseParams.OrderBy},
+// arantesSearch is one combination of the configured list-valued params.
+type arantesSearch struct {
+	label  string
+	params url.Values
+}
+
+// arantesSearches expands params into the cartesian set of queries of a run.
+func arantesSearches(p config.ArantesParams) []arantesSearch {
+	base := url.Values{
+		"cidade":             {p.Cidade},
+		"precoMin":           {p.PrecoMin},
+		"precoMax":           {p.PrecoMax},
+		"quartos":            {p.Quartos},
+		"banheiros":          {p.Banheiros},
+		"tipoOperacao":       {p.TipoOperacao},
+		"id_only_integrador": {p.IDOnlyIntegrador},
+		"id_integrador":      {p.IDIntegrador},
+		"order_by":           {p.OrderBy},
+	}
+	searches := []arantesSearch{{params: base}}
+
+	lists := []struct {
+		key    string
+		values []string
+	}{
+		{"bairro", p.Bairro},
+		{"categoria_imovel", p.CategoriaImovel},
+		{"tipo", p.Tipo},
+	}
+	for _, list := range lists {
+		values := list.values
+		if len(values) == 0 {
+			values = []string{""}
+		}
+
+		var expanded []arantesSearch
+		for _, search := range searches {
+			for _, value := range values {
+				params := make(url.Values, len(search.params)+1)
+				for k, v := range search.params {
+					params[k] = v
+				}
+				params.Set(list.key, value)
+
+				label := search.label
+				if value != "" {
+					label = strings.TrimSpace(label + " " + list.key + "=" + value)
+				}
+				expanded = append(expanded, arantesSearch{label: label, params: params})
+			}
+		}
+		searches = expanded
+	}
+	return searches
+}
+
+// addMatch records that the current search found id and reports whether no
+// earlier search of the run did.
+func (as *ArantesScraper) addMatch(id string) bool {
+	searches, seen := as.matches[id]
+	if as.search != "" {
+		searches = append(searches, as.search)
+	}
+	as.matches[id] = searches
+	return !seen
+}
+
+func (as *ArantesScraper) saveSearchMatches() {
+	for id, searches := range as.matches {
+		if len(searches) == 0 {
+			continue
+		}
+		if err := as.Storage.SaveSearchMatches(id, searches); err != nil {
+			log.Printf("Error saving searches of property %s: %v\n", id, err)
+		}
+	}
+}
+
+// scrapePagination visits the listing pages of search from firstPage on,
+// saving a checkpoint before each one.
+func (as *ArantesScraper) scrapePagination(c *colly.Collector, search arantesSearch, firstPage int) error {
+	as.search = search.label
+	as.pages = newPageTracker()
+	if search.label != "" {
+		log.Printf("Searching %s\n", search.label)
 	}
 
-	for page := 1; page <= as.Config.MaxPages; page++ {
+	// Only a search started from page 1 can tell whether its first page is
+	// empty; a resumed one begins further on.
+	checkFirst := firstPage == 1
+	limit := maxPages(as.Config.MaxPages)
+	for page := firstPage; page <= limit; page++ {
 		select {
 		case <-as.ctx.Done():
 			return as.ctx.Err()
 		default:
-			params := baseParams
-			params.Set("page", strconv.Itoa(page))
-			pageURL := as.Config.BaseURL + "/listagem/?" + params.Encode()
-
-			log.Printf("Visiting page %d: %s\n", page, pageURL)
-			err := c.Visit(pageURL)
-			if err != nil {
-				log.Printf("Failed to visit page %d: %v\n", page, err)
+		}
+		as.saveCheckpoint(search.label, page)
+
+		params := search.params
+		params.Set("page", strconv.Itoa(page))
+		pageURL := as.Config.BaseURL + "/listagem/?" + params.Encode()
+
+		as.pages.startPage()
+		cards := as.Stats().Cards
+		log.Printf("Visiting page %d: %s\n", page, pageURL)
+		err := c.Visit(pageURL)
+		if err != nil {
+			if as.ctx.Err() != nil {
+				return as.ctx.Err()
+			}
+			log.Printf("Failed to visit page %d: %v\n", page, err)
+			as.recordFailure(pageURL, err)
+			continue
+		}
+		as.updateStats(func(s *RunStats) {
+			s.PagesVisited++
+			if checkFirst {
+				// Flag the layout only when no search found listings on its
+				// first page, since a narrow search may be empty.
+				as.listed = as.listed || s.Cards > cards
+				s.EmptyFirstPage = !as.listed
+			}
+		})
+		checkFirst = false
+
+		if reason := as.pages.stopReason(as.Config.NextPageSelector != ""); reason != "" {
+			log.Printf("Stopping pagination after page %d: %s\n", page, reason)
+			as.updateStats(func(s *RunStats) { s.StopReason = reason })
+			return nil
+		}
+		if as.mode == storage.RunModeIncremental {
+			if reason := as.pages.knownStopReason(as.Config.KnownStreakLimit); reason != "" {
+				log.Printf("Stopping incremental run after page %d: %s\n", page, reason)
+				as.updateStats(func(s *RunStats) { s.StopReason = reason })
+				return nil
 			}
 		}
 	}
 
+	log.Printf("Reached max pages (%d)\n", limit)
+	as.updateStats(func(s *RunStats) { s.StopReason = "max pages reached" })
 	return nil
 }
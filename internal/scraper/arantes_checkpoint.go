@@ -0,0 +1,174 @@
+package scraper
+
+import (
+	"encoding/json"
+	"log"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/storage"
+	"time"
+)
+
+// defaultResumeWindow is how old the checkpoint of an interrupted run may be
+// for the next run to resume from it.
+const defaultResumeWindow = 6 * time.Hour
+
+// pendingDetails is a queued property as stored in a checkpoint. It is encoded
+// when queued, before a worker starts filling in its details. FetchedAt is set
+// for properties whose stored details were reused, and Searches is filled in
+// when the checkpoint is saved.
+type pendingDetails struct {
+	Property   *models.Property `json:"property"`
+	RawData    string           `json:"raw_data"`
+	Hash       string           `json:"hash"`
+	DetailsURL string           `json:"details_url"`
+	FetchedAt  time.Time        `json:"fetched_at,omitempty"`
+	Searches   []string         `json:"searches,omitempty"`
+}
+
+// loadCheckpoint returns the checkpoint to resume from, or nil to start from
+// page 1. Checkpoints older than resume_window are discarded.
+func (as *ArantesScraper) loadCheckpoint() *storage.Checkpoint {
+	window := defaultResumeWindow
+	if as.Config.ResumeWindow != 0 {
+		window = as.Config.ResumeWindow.Duration()
+	}
+
+	cp, err := as.Storage.GetCheckpoint("arantes")
+	if err != nil {
+		log.Printf("Error loading checkpoint, starting from page 1: %v\n", err)
+		return nil
+	}
+	if cp == nil {
+		return nil
+	}
+	if window < 0 || time.Since(cp.UpdatedAt) > window {
+		log.Printf("Discarding checkpoint of run %d from %s\n", cp.RunID, cp.UpdatedAt.Local().Format(time.RFC3339))
+		as.deleteCheckpoint()
+		return nil
+	}
+	return cp
+}
+
+// resumeSearches returns the index of the search and the page the run should
+// start at, and queues the details the interrupted run left pending.
+func (as *ArantesScraper) resumeSearches(cp *storage.Checkpoint, searches []arantesSearch) (int, int) {
+	var pending []pendingDetails
+	if cp.Pending != "" {
+		if err := json.Unmarshal([]byte(cp.Pending), &pending); err != nil {
+			log.Printf("Error decoding pending details of checkpoint: %v\n", err)
+		}
+	}
+	log.Printf("Resuming run %d: search %q, page %d, %d pending details\n", cp.RunID, cp.Search, cp.Page, len(pending))
+
+	for _, p := range pending {
+		as.matches[p.Property.ID] = p.Searches
+		job := &detailsJob{
+			property:   p.Property,
+			rawData:    p.RawData,
+			hash:       p.Hash,
+			detailsURL: p.DetailsURL,
+			fetchedAt:  p.FetchedAt,
+		}
+		if job.fetchedAt.IsZero() {
+			as.queueDetails(job)
+		} else {
+			as.queueStored(job)
+		}
+	}
+
+	if cp.Page == 0 {
+		return len(searches), 1
+	}
+	for i, search := range searches {
+		if search.label == cp.Search {
+			return i, cp.Page
+		}
+	}
+	log.Printf("Search %q of the checkpoint is no longer configured, starting from page 1\n", cp.Search)
+	return 0, 1
+}
+
+// queueDetails hands a job to the details pool to fetch its details page.
+func (as *ArantesScraper) queueDetails(job *detailsJob) {
+	as.addPending(job)
+	as.updateStats(func(s *RunStats) { s.DetailsFetched++ })
+	as.pool.fetch(job)
+}
+
+// queueStored hands a job whose stored details were reused straight to the
+// pool's result goroutine.
+func (as *ArantesScraper) queueStored(job *detailsJob) {
+	as.addPending(job)
+	as.pool.process(job)
+}
+
+// addPending keeps a job in the checkpoint until finishProperty is done with
+// it. Jobs must be added before they are handed to the pool.
+func (as *ArantesScraper) addPending(job *detailsJob) {
+	encoded, err := json.Marshal(pendingDetails{
+		Property:   job.property,
+		RawData:    job.rawData,
+		Hash:       job.hash,
+		DetailsURL: job.detailsURL,
+		FetchedAt:  job.fetchedAt,
+	})
+	if err != nil {
+		log.Printf("Error encoding pending details of property %s: %v\n", job.property.ID, err)
+		return
+	}
+	as.mu.Lock()
+	as.pending[job.property.ID] = encoded
+	as.mu.Unlock()
+}
+
+func (as *ArantesScraper) donePending(id string) {
+	as.mu.Lock()
+	delete(as.pending, id)
+	as.mu.Unlock()
+}
+
+// saveCheckpoint records that the run should continue at page of search. A
+// page of 0 means every listing page was visited. It runs on the goroutine
+// that visits the listing pages, the only one that touches matches.
+func (as *ArantesScraper) saveCheckpoint(search string, page int) {
+	as.mu.Lock()
+	pending := make([]pendingDetails, 0, len(as.pending))
+	for id, encoded := range as.pending {
+		var p pendingDetails
+		if err := json.Unmarshal(encoded, &p); err != nil {
+			log.Printf("Error decoding pending details of property %s: %v\n", id, err)
+			continue
+		}
+		p.Searches = as.matches[id]
+		pending = append(pending, p)
+	}
+	as.next = storage.Checkpoint{Source: "arantes", Mode: as.mode, Search: search, Page: page}
+	cp := as.next
+	as.mu.Unlock()
+
+	encoded, err := json.Marshal(pending)
+	if err != nil {
+		log.Printf("Error encoding checkpoint: %v\n", err)
+		return
+	}
+	cp.Pending = string(encoded)
+	cp.RunID = as.runID()
+	if err := as.Storage.SaveCheckpoint(&cp); err != nil {
+		log.Printf("Error saving checkpoint: %v\n", err)
+	}
+}
+
+// updateCheckpoint saves the pending details of an interrupted run without
+// moving its position.
+func (as *ArantesScraper) updateCheckpoint() {
+	as.mu.Lock()
+	next := as.next
+	as.mu.Unlock()
+	as.saveCheckpoint(next.Search, next.Page)
+}
+
+func (as *ArantesScraper) deleteCheckpoint() {
+	if err := as.Storage.DeleteCheckpoint("arantes"); err != nil {
+		log.Printf("Error deleting checkpoint: %v\n", err)
+	}
+}
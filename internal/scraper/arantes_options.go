@@ -0,0 +1,208 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/storage"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// defaultOptionsTTL is how long the cached Arantes filter options are used
+// before the search form is read again.
+const defaultOptionsTTL = 7 * 24 * time.Hour
+
+// arantesOptionFields are the search form selects whose options are cached.
+var arantesOptionFields = []string{"cidade", "bairro", "categoria_imovel", "tipo", "tipoOperacao"}
+
+// Options returns the filter options of the Arantes search form. Cached
+// options are used unless they are older than options_ttl or refresh is set.
+// It must not be called while Scrape runs, since the form is read with ctx.
+func (as *ArantesScraper) Options(ctx context.Context, refresh bool) ([]storage.SourceOption, error) {
+	as.resetStats(ctx, "arantes")
+	return as.options(ctx, refresh)
+}
+
+// options is Options for a run already bound to ctx.
+func (as *ArantesScraper) options(ctx context.Context, refresh bool) ([]storage.SourceOption, error) {
+	ttl := defaultOptionsTTL
+	if as.Config.OptionsTTL > 0 {
+		ttl = as.Config.OptionsTTL.Duration()
+	}
+
+	if !refresh {
+		options, fetchedAt, err := as.Storage.SourceOptions("arantes")
+		if err != nil {
+			return nil, err
+		}
+		if len(options) > 0 && time.Since(fetchedAt) < ttl {
+			return options, nil
+		}
+	}
+
+	options, err := as.fetchOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := as.Storage.ReplaceSourceOptions("arantes", options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+func (as *ArantesScraper) fetchOptions(ctx context.Context) ([]storage.SourceOption, error) {
+	c, err := as.newCollector(as.Config.UserAgent, as.Config.HTTPSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, field := range arantesOptionFields {
+		wanted[field] = true
+	}
+
+	var options []storage.SourceOption
+	c.OnHTML("select", func(e *colly.HTMLElement) {
+		field := strings.TrimSuffix(e.Attr("name"), "[]")
+		if !wanted[field] {
+			return
+		}
+		e.ForEach("option", func(_ int, o *colly.HTMLElement) {
+			value := strings.TrimSpace(o.Attr("value"))
+			label := strings.TrimSpace(o.Text)
+			if value == "" || label == "" {
+				return
+			}
+			options = append(options, storage.SourceOption{Field: field, Value: value, Label: label})
+		})
+	})
+
+	formURL := as.Config.BaseURL + "/listagem/"
+	if err := c.Visit(formURL); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to fetch search form: %w", err)
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no filter options found on %s", formURL)
+	}
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].Field != options[j].Field {
+			return options[i].Field < options[j].Field
+		}
+		return options[i].Label < options[j].Label
+	})
+	return options, nil
+}
+
+// resolveParams returns BaseParams with the names of cities, neighbourhoods,
+// categories, types and operations replaced by their IDs, and the operation
+// of the listings the search returns. Numeric values are used as they are, so
+// the search form is only read when a name or a tipoOperacao is given.
+func (as *ArantesScraper) resolveParams(ctx context.Context) (config.ArantesParams, string, error) {
+	params := as.Config.BaseParams
+	fields := []optionField{
+		{"cidade", []string{params.Cidade}},
+		{"bairro", params.Bairro},
+		{"categoria_imovel", params.CategoriaImovel},
+		{"tipo", params.Tipo},
+		{"tipoOperacao", []string{params.TipoOperacao}},
+	}
+	if !hasOptionNames(fields) && strings.TrimSpace(params.TipoOperacao) == "" {
+		return params, models.OperationRent, nil
+	}
+
+	options, err := as.options(ctx, false)
+	if err != nil {
+		return params, "", fmt.Errorf("failed to load search options: %w", err)
+	}
+	ids, err := resolveOptionNames(options, fields)
+	if err != nil {
+		// The cache may predate a new neighbourhood; read the form once more.
+		log.Printf("Refreshing Arantes search options: %v\n", err)
+		if options, err = as.options(ctx, true); err != nil {
+			return params, "", fmt.Errorf("failed to load search options: %w", err)
+		}
+		if ids, err = resolveOptionNames(options, fields); err != nil {
+			return params, "", err
+		}
+	}
+
+	params.Cidade = ids[0][0]
+	params.Bairro = ids[1]
+	params.CategoriaImovel = ids[2]
+	params.Tipo = ids[3]
+	params.TipoOperacao = ids[4][0]
+	return params, optionOperation(options, params.TipoOperacao), nil
+}
+
+// optionOperation maps the tipoOperacao option with the given ID to an
+// operation by its name. Searches without one are rentals.
+func optionOperation(options []storage.SourceOption, id string) string {
+	for _, option := range options {
+		if option.Field != "tipoOperacao" || option.Value != id {
+			continue
+		}
+		label := models.FoldText(option.Label)
+		if strings.Contains(label, "venda") || strings.Contains(label, "compra") {
+			return models.OperationSale
+		}
+	}
+	return models.OperationRent
+}
+
+// optionField is a search param together with its configured values.
+type optionField struct {
+	name   string
+	values []string
+}
+
+func hasOptionNames(fields []optionField) bool {
+	for _, field := range fields {
+		for _, value := range field.values {
+			if !isOptionID(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isOptionID(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.Trim(value, "0123456789") == ""
+}
+
+// resolveOptionNames maps the values of every field to option IDs, matching
+// names regardless of case and accents.
+func resolveOptionNames(options []storage.SourceOption, fields []optionField) ([][]string, error) {
+	byLabel := make(map[string]string)
+	for _, option := range options {
+		byLabel[option.Field+"\x00"+models.FoldText(option.Label)] = option.Value
+	}
+
+	resolved := make([][]string, len(fields))
+	for i, field := range fields {
+		ids := make([]string, len(field.values))
+		for j, value := range field.values {
+			if isOptionID(value) {
+				ids[j] = strings.TrimSpace(value)
+				continue
+			}
+			id, ok := byLabel[field.name+"\x00"+models.FoldText(value)]
+			if !ok {
+				return nil, fmt.Errorf("unknown %s %q; run \"rent-watcher sources arantes options\" to list the valid values", field.name, value)
+			}
+			ids[j] = id
+		}
+		resolved[i] = ids
+	}
+	return resolved, nil
+}
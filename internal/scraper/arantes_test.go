@@ -0,0 +1,364 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/storage"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// arantesSite serves the Arantes fixtures: the listing page each page number
+// maps to, the empty page past them and one details page for every listing.
+type arantesSite struct {
+	mu      sync.Mutex
+	pages   map[string]string
+	listing []string
+	details []string
+}
+
+func (s *arantesSite) route(r *http.Request) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.URL.Path == "/listagem/":
+		s.listing = append(s.listing, r.URL.Query().Get("page"))
+		if name, ok := s.pages[r.URL.Query().Get("page")]; ok {
+			return name
+		}
+		return "arantes_empty.html"
+	case strings.HasPrefix(r.URL.Path, "/detalhes/"):
+		s.details = append(s.details, strings.TrimPrefix(r.URL.Path, "/detalhes/"))
+		return "arantes_details.html"
+	}
+	return ""
+}
+
+func (s *arantesSite) setPage(page, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[page] = name
+}
+
+// visited returns the listing pages and the details pages requested since the
+// last call.
+func (s *arantesSite) visited() (pages, details []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pages, details = s.listing, s.details
+	s.listing, s.details = nil, nil
+	return pages, details
+}
+
+func serveArantes(t *testing.T) (*arantesSite, string) {
+	t.Helper()
+	site := &arantesSite{pages: map[string]string{
+		"1": "arantes_page1.html",
+		"2": "arantes_page2.html",
+	}}
+	srv := serveFixtures(t, "text/html; charset=utf-8", site.route)
+	return site, srv.URL
+}
+
+func newTestArantesScraper(t *testing.T, baseURL string, store storage.Storage, cfg ArantesConfig) (*ArantesScraper, *testNotifier) {
+	t.Helper()
+	cfg.BaseURL = baseURL
+	if cfg.MaxPages == 0 {
+		cfg.MaxPages = 10
+	}
+	notifier := &testNotifier{}
+	return NewArantesScraper(cfg, 0, 0, store, notifier, nil), notifier
+}
+
+// recordFullSweep stores a completed full run, so the next incremental run
+// does not have to sweep every page.
+func recordFullSweep(t *testing.T, store storage.Storage) {
+	t.Helper()
+	run, err := store.StartRun("arantes", storage.RunModeFull)
+	if err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	run.Status = storage.RunStatusCompleted
+	if err := store.FinishRun(run); err != nil {
+		t.Fatalf("FinishRun: %v", err)
+	}
+}
+
+func TestArantesScraperFullSweep(t *testing.T) {
+	site, baseURL := serveArantes(t)
+	as, notifier := newTestArantesScraper(t, baseURL, newTestStorage(t), ArantesConfig{})
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	pages, details := site.visited()
+	if strings.Join(pages, ",") != "1,2,3" || len(details) != 5 {
+		t.Errorf("visited pages %v and details %v, want pages 1 to 3 and 5 details", pages, details)
+	}
+	stats := as.Stats()
+	if stats.Mode != storage.RunModeFull || stats.StopReason != "empty page" || stats.PropertiesProcessed != 5 {
+		t.Errorf("stats = %s, want a full run of 5 properties stopped by the empty page", stats)
+	}
+
+	property := notifier.notified()["1001"]
+	if property == nil {
+		t.Fatalf("property 1001 not notified: %v", notifier.notified())
+	}
+	for _, tc := range []struct{ field, got, want string }{
+		{"price", property.Price, "1.500,00"},
+		{"condominio", property.Condominio, "300,00"},
+		{"total_price", property.TotalPrice, "1800.00"},
+		{"quartos", property.Quartos, "2"},
+		{"suites", property.Suites, "1"},
+		{"url", property.URL, baseURL + "/detalhes/1001"},
+		{"first_foto", property.FirstPhoto, baseURL + "/fotos/1001.jpg"},
+		{"aceita_pets", property.Attributes["aceita_pets"], "true"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+}
+
+func TestArantesScraperIncrementalStopsAtKnownListings(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		known      []string
+		streak     int
+		fullSweep  bool
+		wantMode   string
+		wantPages  string
+		wantReason string
+	}{
+		{"only known page", []string{"1004", "1005"}, 0, true, storage.RunModeIncremental, "1,2", "only known listings"},
+		{"known streak", []string{"1002", "1003"}, 2, true, storage.RunModeIncremental, "1", "2 consecutive known listings"},
+		{"full sweep due", []string{"1004", "1005"}, 0, false, storage.RunModeFull, "1,2,3", "empty page"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTestStorage(t)
+			for _, id := range tc.known {
+				if err := store.SaveOrUpdateProperty(&models.Property{ID: id, Source: "arantes", Price: "1000"}, ""); err != nil {
+					t.Fatalf("SaveOrUpdateProperty: %v", err)
+				}
+			}
+			if tc.fullSweep {
+				recordFullSweep(t, store)
+			}
+
+			site, baseURL := serveArantes(t)
+			as, notifier := newTestArantesScraper(t, baseURL, store, ArantesConfig{Incremental: true, KnownStreakLimit: tc.streak})
+			if err := as.Scrape(context.Background()); err != nil {
+				t.Fatalf("Scrape: %v", err)
+			}
+
+			pages, _ := site.visited()
+			if got := strings.Join(pages, ","); got != tc.wantPages {
+				t.Errorf("visited pages %s, want %s", got, tc.wantPages)
+			}
+			stats := as.Stats()
+			if stats.Mode != tc.wantMode || stats.StopReason != tc.wantReason {
+				t.Errorf("stats = %s, want mode %s stopped by %q", stats, tc.wantMode, tc.wantReason)
+			}
+			for _, id := range tc.known {
+				if notifier.notified()[id] != nil {
+					t.Errorf("known property %s notified again", id)
+				}
+			}
+		})
+	}
+}
+
+func TestArantesScraperSkipsUnchangedDetails(t *testing.T) {
+	store := newTestStorage(t)
+	site, baseURL := serveArantes(t)
+	as, _ := newTestArantesScraper(t, baseURL, store, ArantesConfig{})
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("first Scrape: %v", err)
+	}
+	if _, details := site.visited(); len(details) != 5 {
+		t.Fatalf("first run fetched details %v, want all 5", details)
+	}
+
+	// Only the card of 1001 changed since the first run.
+	site.setPage("1", "arantes_page1_changed.html")
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("second Scrape: %v", err)
+	}
+	if _, details := site.visited(); strings.Join(details, ",") != "1001" {
+		t.Errorf("second run fetched details %v, want only 1001", details)
+	}
+	stats := as.Stats()
+	if stats.DetailsFetched != 1 || stats.DetailsSkipped != 4 || stats.PropertiesProcessed != 5 {
+		t.Errorf("stats = %s, want 1 details fetched, 4 skipped and 5 properties", stats)
+	}
+
+	// Skipped properties keep the details stored by the first run.
+	property, err := store.GetProperty("1002")
+	if err != nil {
+		t.Fatalf("GetProperty: %v", err)
+	}
+	if property.Condominio != "300,00" || property.Suites != "1" || property.Attributes["aceita_pets"] != "true" {
+		t.Errorf("property 1002 = %+v, want the stored details", property)
+	}
+	if property, _ := store.GetProperty("1001"); property == nil || property.Price != "1.400,00" {
+		t.Errorf("property 1001 = %+v, want the new price", property)
+	}
+
+	// Details older than details_ttl are fetched again even if the card is the same.
+	as.Config.DetailsTTL = config.Duration(time.Nanosecond)
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("third Scrape: %v", err)
+	}
+	if _, details := site.visited(); len(details) != 5 {
+		t.Errorf("run after details_ttl fetched details %v, want all 5", details)
+	}
+}
+
+// interruptArantes runs a scrape that is cancelled while page 2 is loading.
+func interruptArantes(t *testing.T, store storage.Storage, cfg ArantesConfig) (*arantesSite, *ArantesScraper, *testNotifier) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	site := &arantesSite{pages: map[string]string{"1": "arantes_page1.html", "2": "arantes_page2.html"}}
+	var once sync.Once
+	srv := serveFixtures(t, "text/html; charset=utf-8", func(r *http.Request) string {
+		name := site.route(r)
+		if r.URL.Path == "/listagem/" && r.URL.Query().Get("page") == "2" && ctx.Err() == nil {
+			once.Do(cancel)
+			<-r.Context().Done()
+		}
+		return name
+	})
+
+	as, notifier := newTestArantesScraper(t, srv.URL, store, cfg)
+	if err := as.Scrape(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted Scrape = %v, want context.Canceled", err)
+	}
+	site.visited()
+	return site, as, notifier
+}
+
+func TestArantesScraperResumesFromCheckpoint(t *testing.T) {
+	store := newTestStorage(t)
+	site, as, notifier := interruptArantes(t, store, ArantesConfig{})
+
+	cp, err := store.GetCheckpoint("arantes")
+	if err != nil || cp == nil {
+		t.Fatalf("GetCheckpoint = %v, %v; want the checkpoint of the interrupted run", cp, err)
+	}
+	if cp.Page != 2 || cp.Mode != storage.RunModeFull {
+		t.Errorf("checkpoint = %+v, want page 2 of a full run", cp)
+	}
+
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("resumed Scrape: %v", err)
+	}
+	if pages, _ := site.visited(); strings.Join(pages, ",") != "2,3" {
+		t.Errorf("resumed run visited pages %v, want 2 and 3", pages)
+	}
+	if stats := as.Stats(); !stats.Resumed {
+		t.Errorf("stats = %s, want a resumed run", stats)
+	}
+
+	// Every listing is saved once, whether it was finished before the
+	// interruption or left pending in the checkpoint.
+	for _, id := range []string{"1001", "1002", "1003", "1004", "1005"} {
+		if exists, err := store.PropertyExists(id); err != nil || !exists {
+			t.Errorf("property %s not saved (err %v)", id, err)
+		}
+	}
+	notifier.mu.Lock()
+	notifications := len(notifier.properties)
+	notifier.mu.Unlock()
+	if notifications != 5 {
+		t.Errorf("%d notifications, want one per listing", notifications)
+	}
+
+	if cp, err := store.GetCheckpoint("arantes"); err != nil || cp != nil {
+		t.Errorf("GetCheckpoint after a completed run = %+v, %v; want none", cp, err)
+	}
+}
+
+func TestArantesScraperDiscardsStaleCheckpoint(t *testing.T) {
+	store := newTestStorage(t)
+	site, as, _ := interruptArantes(t, store, ArantesConfig{})
+
+	as.Config.ResumeWindow = config.Duration(-1)
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if pages, _ := site.visited(); strings.Join(pages, ",") != "1,2,3" {
+		t.Errorf("visited pages %v, want a run from page 1", pages)
+	}
+	if stats := as.Stats(); stats.Resumed {
+		t.Errorf("stats = %s, want a run that did not resume", stats)
+	}
+}
+
+// matchStorage records the search matches saved for each property.
+type matchStorage struct {
+	storage.Storage
+	mu      sync.Mutex
+	matches map[string][]string
+}
+
+func (s *matchStorage) SaveSearchMatches(propertyID string, searches []string) error {
+	s.mu.Lock()
+	s.matches[propertyID] = append(s.matches[propertyID], searches...)
+	s.mu.Unlock()
+	return s.Storage.SaveSearchMatches(propertyID, searches)
+}
+
+func TestArantesScraperFanOutProcessesListingsOnce(t *testing.T) {
+	// Neighbourhood 1 has only the first page; neighbourhood 2 has both, so
+	// the listings of page 1 are found by both searches.
+	site := &arantesSite{pages: map[string]string{"1": "arantes_page1.html"}}
+	srv := serveFixtures(t, "text/html; charset=utf-8", func(r *http.Request) string {
+		name := site.route(r)
+		if r.URL.Path == "/listagem/" && r.URL.Query().Get("bairro") == "2" && r.URL.Query().Get("page") == "2" {
+			return "arantes_page2.html"
+		}
+		return name
+	})
+
+	store := &matchStorage{Storage: newTestStorage(t), matches: make(map[string][]string)}
+	as, notifier := newTestArantesScraper(t, srv.URL, store, ArantesConfig{
+		BaseParams: config.ArantesParams{Bairro: config.StringList{"1", "2"}},
+	})
+	if err := as.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	pages, details := site.visited()
+	if strings.Join(pages, ",") != "1,2,1,2,3" {
+		t.Errorf("visited pages %v, want 1 and 2 of the first search and 1 to 3 of the second", pages)
+	}
+	if len(details) != 5 {
+		t.Errorf("visited details %v, want each of the 5 listings once", details)
+	}
+	if stats := as.Stats(); stats.PropertiesProcessed != 5 {
+		t.Errorf("stats = %s, want 5 properties processed", stats)
+	}
+	if n := len(notifier.notified()); n != 5 || len(notifier.properties) != 5 {
+		t.Errorf("notified %d properties (%d distinct), want 5 once each", len(notifier.properties), n)
+	}
+
+	for _, tc := range []struct{ id, want string }{
+		{"1001", "bairro=1,bairro=2"},
+		{"1003", "bairro=1,bairro=2"},
+		{"1004", "bairro=2"},
+	} {
+		store.mu.Lock()
+		got := strings.Join(store.matches[tc.id], ",")
+		store.mu.Unlock()
+		if got != tc.want {
+			t.Errorf("searches of %s = %q, want %q", tc.id, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/storage"
+	"strings"
+)
+
+// ErrLayoutDrift is returned by Scrape when a run looks like the site changed
+// its layout under the scraper.
+var ErrLayoutDrift = errors.New("layout drift detected")
+
+const (
+	defaultMaxFailureRatio = 0.5
+	defaultMinYieldRatio   = 0.25
+	defaultYieldHistory    = 5
+	// minYieldRuns and minYieldAverage keep the yield check quiet until there
+	// is enough history for the average to mean something.
+	minYieldRuns    = 3
+	minYieldAverage = 5
+)
+
+// propertyGetters reads the fields that can be listed in required_fields.
+var propertyGetters = map[string]func(p *models.Property) string{
+	"id":          func(p *models.Property) string { return p.ID },
+	"url":         func(p *models.Property) string { return p.URL },
+	"first_photo": func(p *models.Property) string { return p.FirstPhoto },
+	"price":       func(p *models.Property) string { return p.Price },
+	"logradouro":  func(p *models.Property) string { return p.Logradouro },
+	"bairro":      func(p *models.Property) string { return p.Bairro },
+	"cidade":      func(p *models.Property) string { return p.Cidade },
+	"metragem":    func(p *models.Property) string { return p.Metragem },
+	"quartos":     func(p *models.Property) string { return p.Quartos },
+	"banheiros":   func(p *models.Property) string { return p.Banheiros },
+	"suites":      func(p *models.Property) string { return p.Suites },
+	"garagens":    func(p *models.Property) string { return p.Garagens },
+	"tipo_imovel": func(p *models.Property) string { return p.TipoImovel },
+	"condominio":  func(p *models.Property) string { return p.Condominio },
+}
+
+// missingFields returns the required fields property has no value for.
+// Unknown field names are read from the property's attributes.
+func missingFields(property *models.Property, required []string) []string {
+	var missing []string
+	for _, field := range required {
+		value := property.Attributes[field]
+		if get, ok := propertyGetters[field]; ok {
+			value = get(property)
+		}
+		if strings.TrimSpace(value) == "" {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// hasRequiredFields counts and logs properties missing a required field, so
+// checkDrift can tell when a selector stopped matching.
+func (bs *BaseScraper) hasRequiredFields(property *models.Property, required []string) bool {
+	missing := missingFields(property, required)
+	if len(missing) == 0 {
+		return true
+	}
+	log.Printf("Skipping property %q from %s: missing %s\n", property.ID, property.Source, strings.Join(missing, ", "))
+	bs.updateStats(func(s *RunStats) { s.MissingFields++ })
+	return false
+}
+
+// checkDrift runs the layout sanity checks on the finished run. On drift it
+// alerts the admin channel and returns an error wrapping ErrLayoutDrift.
+func (bs *BaseScraper) checkDrift(drift config.DriftConfig) error {
+	stats := bs.Stats()
+	reasons := driftReasons(stats, drift)
+
+	if reason := bs.yieldDrift(stats, drift); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	message := strings.Join(reasons, "; ")
+	if err := bs.Notifier.NotifyAlert(stats.Source, "Layout drift: "+message); err != nil {
+		log.Printf("[%s] Error sending drift alert: %v\n", stats.Source, err)
+	}
+	return fmt.Errorf("%w in %s: %s", ErrLayoutDrift, stats.Source, message)
+}
+
+func driftReasons(stats RunStats, drift config.DriftConfig) []string {
+	maxRatio := drift.MaxFailureRatio
+	if maxRatio <= 0 {
+		maxRatio = defaultMaxFailureRatio
+	}
+
+	var reasons []string
+	if stats.EmptyFirstPage {
+		reasons = append(reasons, "no listings found on page 1")
+	}
+	if stats.Cards > 0 {
+		if ratio := float64(stats.ParseFailures) / float64(stats.Cards); ratio > maxRatio {
+			reasons = append(reasons, fmt.Sprintf("%d of %d listings could not be parsed", stats.ParseFailures, stats.Cards))
+		}
+		if ratio := float64(stats.MissingFields) / float64(stats.Cards); ratio > maxRatio {
+			reasons = append(reasons, fmt.Sprintf("%d of %d listings miss required fields", stats.MissingFields, stats.Cards))
+		}
+		if ratio := float64(stats.Rejected) / float64(stats.Cards); ratio > maxRatio {
+			reasons = append(reasons, fmt.Sprintf("%d of %d listings failed validation", stats.Rejected, stats.Cards))
+		}
+	}
+	return reasons
+}
+
+// yieldDrift compares the listings found with the average of recent runs in
+// the same mode. Resumed runs only see part of the listings and are skipped.
+func (bs *BaseScraper) yieldDrift(stats RunStats, drift config.DriftConfig) string {
+	if stats.Mode == "" || stats.Mode == storage.RunModeIncremental || stats.Resumed {
+		return ""
+	}
+
+	history := drift.YieldHistory
+	if history <= 0 {
+		history = defaultYieldHistory
+	}
+	minRatio := drift.MinYieldRatio
+	if minRatio <= 0 {
+		minRatio = defaultMinYieldRatio
+	}
+
+	runs, err := bs.Storage.RecentRuns(stats.Source, stats.Mode, history)
+	if err != nil {
+		log.Printf("[%s] Error loading recent runs: %v\n", stats.Source, err)
+		return ""
+	}
+	if len(runs) < minYieldRuns {
+		return ""
+	}
+
+	total := 0
+	for _, run := range runs {
+		total += run.PropertiesProcessed
+	}
+	average := float64(total) / float64(len(runs))
+	if average < minYieldAverage || float64(stats.PropertiesProcessed) >= average*minRatio {
+		return ""
+	}
+	return fmt.Sprintf("processed %d listings, recent average is %.0f", stats.PropertiesProcessed, average)
+}
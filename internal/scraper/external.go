@@ -0,0 +1,230 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/notifier"
+	"rent-watcher/internal/storage"
+	"sync"
+	"time"
+)
+
+// ExternalProtocolVersion is the version of the JSON Lines protocol spoken
+// with external scraper processes:
+//
+//   - stdin receives a single ExternalRequest object, then is closed;
+//   - the first stdout line must be {"protocol_version": <version>};
+//   - every following stdout line is one models.Property as JSON;
+//   - stderr is forwarded to the logs line by line;
+//   - a non-zero exit status fails the run.
+const ExternalProtocolVersion = 1
+
+const (
+	defaultExternalTimeout = 10 * time.Minute
+	externalWaitDelay      = 5 * time.Second
+	maxExternalLineSize    = 10 * 1024 * 1024
+)
+
+// ExternalRequest is written to the stdin of external scrapers.
+type ExternalRequest struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Source          string          `json:"source"`
+	Params          json.RawMessage `json:"params,omitempty"`
+}
+
+type externalHandshake struct {
+	ProtocolVersion *int `json:"protocol_version"`
+}
+
+// ExternalScraper runs a configured command and feeds the properties it
+// prints into the regular ProcessProperty pipeline.
+type ExternalScraper struct {
+	BaseScraper
+	Config ExternalConfig
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type ExternalConfig config.ExternalScraperConfig
+
+func NewExternalScraper(config ExternalConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) (*ExternalScraper, error) {
+	if config.Name == "" || config.Command == "" {
+		return nil, fmt.Errorf("external scraper requires a name and a command")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExternalScraper{
+		BaseScraper: BaseScraper{
+			Storage:             storage,
+			Notifier:            notifier,
+			GeolocationProvider: geoProvider,
+			DestinationLat:      destinationLat,
+			DestinationLng:      destinationLng,
+		},
+		Config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+func (es *ExternalScraper) Scrape(ctx context.Context) (err error) {
+	timeout := es.Config.Timeout.Duration()
+	if timeout <= 0 {
+		timeout = defaultExternalTimeout
+	}
+
+	es.mu.Lock()
+	es.ctx, es.cancel = context.WithTimeout(ctx, timeout)
+	es.mu.Unlock()
+	defer es.cancel()
+	es.resetStats(es.ctx, es.Config.Name)
+	es.beginRun(es.Config.Name, storage.RunModeFull)
+	defer func() { es.finishRun(err) }()
+
+	request, err := json.Marshal(ExternalRequest{
+		ProtocolVersion: ExternalProtocolVersion,
+		Source:          es.Config.Name,
+		Params:          es.Config.Params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	cmd := exec.CommandContext(es.ctx, es.Config.Command, es.Config.Args...)
+	cmd.Dir = es.Config.Dir
+	cmd.Env = append(os.Environ(), fmt.Sprintf("RENT_WATCHER_PROTOCOL_VERSION=%d", ExternalProtocolVersion))
+	for key, value := range es.Config.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stdin = bytes.NewReader(append(request, '\n'))
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = externalWaitDelay
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stderr: %w", err)
+	}
+
+	log.Printf("[%s] Starting external scraper: %s\n", es.Config.Name, cmd.String())
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", es.Config.Command, err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		es.logStderr(stderr)
+	}()
+
+	readErr := es.readRecords(stdout)
+	if readErr != nil {
+		// Drain the rest of the output so the process is not blocked on a full pipe.
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	wg.Wait()
+	waitErr := cmd.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if es.ctx.Err() != nil {
+		// The source's own timeout is not the run's, so later sources still run.
+		return fmt.Errorf("external scraper %s timed out after %s", es.Config.Name, timeout)
+	}
+	if waitErr != nil {
+		return fmt.Errorf("external scraper %s failed: %w", es.Config.Name, waitErr)
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return es.checkDrift(es.Config.Drift)
+}
+
+func (es *ExternalScraper) readRecords(stdout io.Reader) error {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxExternalLineSize)
+
+	handshakeDone := false
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		if !handshakeDone {
+			var handshake externalHandshake
+			if err := json.Unmarshal(data, &handshake); err != nil || handshake.ProtocolVersion == nil {
+				return fmt.Errorf("line %d: expected protocol handshake, got %q", line, data)
+			}
+			if *handshake.ProtocolVersion != ExternalProtocolVersion {
+				return fmt.Errorf("unsupported protocol version %d (expected %d)", *handshake.ProtocolVersion, ExternalProtocolVersion)
+			}
+			handshakeDone = true
+			continue
+		}
+
+		es.updateStats(func(s *RunStats) { s.Cards++ })
+		var property models.Property
+		if err := json.Unmarshal(data, &property); err != nil {
+			log.Printf("[%s] Skipping invalid record on line %d: %v\n", es.Config.Name, line, err)
+			es.updateStats(func(s *RunStats) { s.ParseFailures++ })
+			continue
+		}
+		if property.ID == "" {
+			log.Printf("[%s] Skipping record without id on line %d\n", es.Config.Name, line)
+			es.updateStats(func(s *RunStats) { s.MissingFields++ })
+			continue
+		}
+
+		property.ID = es.Config.Name + ":" + property.ID
+		property.Source = es.Config.Name
+		if !es.hasRequiredFields(&property, es.Config.Drift.RequiredFields) {
+			continue
+		}
+
+		if err := es.ProcessProperty(es.ctx, &property, string(data)); err != nil {
+			log.Printf("[%s] Error processing property: %v\n", es.Config.Name, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("failed to read output: %w", err)
+	}
+	if !handshakeDone {
+		return fmt.Errorf("external scraper %s exited without a protocol handshake", es.Config.Name)
+	}
+	return nil
+}
+
+func (es *ExternalScraper) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), maxExternalLineSize)
+	for scanner.Scan() {
+		log.Printf("[%s] stderr: %s\n", es.Config.Name, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("[%s] Error reading stderr: %v\n", es.Config.Name, err)
+		// Keep draining so the process is not blocked on a full pipe.
+		_, _ = io.Copy(io.Discard, stderr)
+	}
+}
@@ -0,0 +1,165 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"rent-watcher/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+// externalHelperEnv makes the test binary act as an external scraper; see
+// TestMain.
+const externalHelperEnv = "RENT_WATCHER_TEST_EXTERNAL"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(externalHelperEnv); mode != "" {
+		os.Exit(runExternalHelper(mode))
+	}
+	os.Exit(m.Run())
+}
+
+// runExternalHelper plays an external scraper whose behaviour is given by mode.
+func runExternalHelper(mode string) int {
+	var request ExternalRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid request: %v\n", err)
+		return 2
+	}
+	var params struct {
+		Bairro string `json:"bairro"`
+	}
+	json.Unmarshal(request.Params, &params)
+
+	switch mode {
+	case "ok":
+		fmt.Println(`{"protocol_version": 1}`)
+		fmt.Fprintln(os.Stderr, "starting search")
+		fmt.Printf(`{"id": "1", "preco": "1500", "condominio": "200", "bairro": %q}`+"\n", params.Bairro)
+		fmt.Println()
+		fmt.Println(`{"id": 2}`)
+		fmt.Println(`{"preco": "900"}`)
+		fmt.Printf(`{"id": "3", "preco": "900000", "operacao": "sale", "bairro": %q}`+"\n", params.Bairro)
+	case "no-handshake":
+		fmt.Println(`{"id": "1", "preco": "1500"}`)
+	case "bad-version":
+		fmt.Println(`{"protocol_version": 2}`)
+	case "exit-error":
+		fmt.Println(`{"protocol_version": 1}`)
+		fmt.Fprintln(os.Stderr, "login failed")
+		return 3
+	case "long-stderr":
+		// A line longer than the scanner allows, followed by enough output to
+		// fill the pipe if nobody keeps reading it.
+		fmt.Fprintln(os.Stderr, strings.Repeat("x", maxExternalLineSize+1))
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintln(os.Stderr, strings.Repeat("y", 1024))
+		}
+		fmt.Println(`{"protocol_version": 1}`)
+		fmt.Println(`{"id": "1", "preco": "1500"}`)
+	case "hang":
+		fmt.Println(`{"protocol_version": 1}`)
+		time.Sleep(time.Minute)
+	}
+	return 0
+}
+
+func newTestExternalScraper(t *testing.T, mode string, timeout time.Duration) (*ExternalScraper, *testNotifier) {
+	t.Helper()
+	notifier := &testNotifier{}
+	es, err := NewExternalScraper(ExternalConfig{
+		Name:    "externo",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^$"},
+		Env:     map[string]string{externalHelperEnv: mode},
+		Timeout: config.Duration(timeout),
+		Params:  json.RawMessage(`{"bairro": "Jóquei"}`),
+	}, 0, 0, newTestStorage(t), notifier, nil)
+	if err != nil {
+		t.Fatalf("NewExternalScraper: %v", err)
+	}
+	return es, notifier
+}
+
+func TestExternalScraper(t *testing.T) {
+	es, notifier := newTestExternalScraper(t, "ok", 0)
+	if err := es.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	stats := es.Stats()
+	if stats.Cards != 4 || stats.ParseFailures != 1 || stats.MissingFields != 1 || stats.PropertiesProcessed != 2 {
+		t.Errorf("stats = %s, want 4 cards, 1 parse failure, 1 missing id and 2 properties", stats)
+	}
+
+	notified := notifier.notified()
+	rental := notified["externo:1"]
+	if rental == nil {
+		t.Fatalf("property externo:1 not notified: %v", notified)
+	}
+	for _, tc := range []struct{ field, got, want string }{
+		{"source", rental.Source, "externo"},
+		{"bairro", rental.Bairro, "Jóquei"},
+		{"total_price", rental.TotalPrice, "1700.00"},
+		{"operation", rental.Operation, "rent"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+	if sale := notified["externo:3"]; sale == nil || !sale.IsSale() {
+		t.Errorf("externo:3 = %+v, want a sale", sale)
+	}
+}
+
+func TestExternalScraperProtocolErrors(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		want string
+	}{
+		{"no-handshake", "expected protocol handshake"},
+		{"bad-version", "unsupported protocol version 2"},
+		{"exit-error", "external scraper externo failed: exit status 3"},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			es, notifier := newTestExternalScraper(t, tc.mode, 0)
+			err := es.Scrape(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Scrape = %v, want an error containing %q", err, tc.want)
+			}
+			if len(notifier.notified()) != 0 {
+				t.Errorf("notified %v, want nothing", notifier.notified())
+			}
+		})
+	}
+}
+
+func TestExternalScraperDrainsStderrAfterReadError(t *testing.T) {
+	es, notifier := newTestExternalScraper(t, "long-stderr", 30*time.Second)
+	if err := es.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+	if notifier.notified()["externo:1"] == nil {
+		t.Errorf("property written after the long stderr line not notified")
+	}
+}
+
+func TestExternalScraperTimeout(t *testing.T) {
+	es, _ := newTestExternalScraper(t, "hang", 200*time.Millisecond)
+	start := time.Now()
+	err := es.Scrape(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("Scrape = %v, want a timeout error", err)
+	}
+	// The source's timeout must not look like the run's own deadline.
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Scrape error %v matches context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > externalWaitDelay+5*time.Second {
+		t.Errorf("Scrape took %s to stop", elapsed)
+	}
+}
@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rent-watcher/internal/database"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/storage"
+	"sync"
+	"testing"
+)
+
+type testNotifier struct {
+	mu         sync.Mutex
+	properties []*models.Property
+	alerts     []string
+}
+
+func (n *testNotifier) NotifyNewProperty(property *models.Property) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	copied := *property
+	n.properties = append(n.properties, &copied)
+	return nil
+}
+
+func (n *testNotifier) NotifyAlert(source, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, source+": "+message)
+	return nil
+}
+
+func (n *testNotifier) Close() error { return nil }
+
+func (n *testNotifier) notified() map[string]*models.Property {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	byID := make(map[string]*models.Property)
+	for _, p := range n.properties {
+		byID[p.ID] = p
+	}
+	return byID
+}
+
+// newTestStorage returns storage backed by an in-memory database private to t.
+func newTestStorage(t *testing.T) storage.Storage {
+	t.Helper()
+	db, err := database.Init("file:" + url.PathEscape(t.Name()) + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return storage.NewSQLStorage(db)
+}
+
+// serveFixtures starts a server that answers with the fixture route returns
+// for each request, or 404 when it returns "".
+func serveFixtures(t *testing.T, contentType string, route func(r *http.Request) string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := route(r)
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Errorf("failed to read fixture: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
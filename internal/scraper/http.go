@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/transport"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// httpTransport returns the transport stack of a source. The cache is on top,
+// so hits skip every other layer. Retries sit above the politeness limits,
+// so every retry is rate limited too, and rotation sits below them, so every
+// retry can go out through another proxy. A recording archive sits just
+// above rotation to capture what goes over the network; a replaying archive
+// replaces the network, the cache, the session and the politeness limits
+// altogether. The session sits just below the cache, so its login requests and
+// the requests it sends again are retried and rate limited like any other. The
+// retry budget and the rotation session are renewed at the start of each run.
+// Every request is bound to the context of the current run. The stack is
+// built once and shared by every collector and client of the source.
+func (bs *BaseScraper) httpTransport(userAgent string, settings config.HTTPSettings) (http.RoundTripper, error) {
+	bs.transportMu.Lock()
+	defer bs.transportMu.Unlock()
+	if bs.transport != nil {
+		return bs.transport, nil
+	}
+
+	rt, err := bs.newStack(userAgent, settings)
+	if err != nil {
+		return nil, err
+	}
+	bs.transport = transport.NewBind(rt, bs.runContext)
+	return bs.transport, nil
+}
+
+func (bs *BaseScraper) newStack(userAgent string, settings config.HTTPSettings) (http.RoundTripper, error) {
+	if settings.Archive.Mode == transport.ArchiveReplay {
+		archive, err := transport.NewArchive(nil, settings.Archive)
+		if err != nil {
+			return nil, err
+		}
+		return bs.newRetry(archive, settings.Retry, nil), nil
+	}
+
+	rotation := transport.NewRotation(http.DefaultTransport, settings.Rotation)
+	var network http.RoundTripper = rotation
+	if settings.Archive.Mode != "" {
+		archive, err := transport.NewArchive(rotation, settings.Archive)
+		if err != nil {
+			return nil, err
+		}
+		network = archive
+	}
+
+	polite := transport.NewPolite(network, settings.Politeness, userAgent)
+	var rt http.RoundTripper = bs.newRetry(polite, settings.Retry, rotation)
+	if settings.Session.Enabled() {
+		session, err := transport.NewSession(rt, settings.Session)
+		if err != nil {
+			return nil, err
+		}
+		rt = session
+	}
+	if settings.Cache.Dir == "" {
+		return rt, nil
+	}
+
+	cache := transport.NewCache(rt, settings.Cache)
+	cache.Revalidate = func(req *http.Request) bool {
+		return bs.listingPage != nil && bs.listingPage(req.URL)
+	}
+	cache.OnResult = func(_ *http.Request, result string) {
+		bs.updateStats(func(s *RunStats) {
+			switch result {
+			case transport.CacheHit:
+				s.CacheHits++
+			case transport.CacheRevalidated:
+				s.CacheRevalidated++
+			default:
+				s.CacheMisses++
+			}
+		})
+	}
+	return cache, nil
+}
+
+// everyPage is the listingPage of sources that only fetch listing pages.
+func everyPage(*url.URL) bool {
+	return true
+}
+
+// newRetry wraps next with the retry layer and registers the layers whose
+// per-run state resetStats renews.
+func (bs *BaseScraper) newRetry(next http.RoundTripper, settings config.RetryConfig, rotation *transport.Rotation) *transport.Retry {
+	retry := transport.NewRetry(next, settings)
+	retry.OnRetry = func(req *http.Request, attempt int, reason string) {
+		log.Printf("Retrying %s after attempt %d: %s\n", req.URL, attempt, reason)
+		bs.updateStats(func(s *RunStats) { s.Retries++ })
+	}
+
+	bs.statsMu.Lock()
+	bs.retry = retry
+	bs.rotation = rotation
+	bs.statsMu.Unlock()
+	return retry
+}
+
+// newCollector creates a colly collector whose requests go through the
+// source's transport stack. Clones share the same transport and limits.
+// Sources with a session keep their cookies there instead of in colly.
+func (bs *BaseScraper) newCollector(userAgent string, settings config.HTTPSettings) (*colly.Collector, error) {
+	rt, err := bs.httpTransport(userAgent, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	c := colly.NewCollector(
+		colly.AllowURLRevisit(),
+		colly.UserAgent(userAgent),
+	)
+	c.WithTransport(rt)
+	if settings.Session.Enabled() {
+		c.DisableCookies()
+	}
+	return c, nil
+}
+
+// newHTTPClient creates the http.Client of a JSON API source on top of the
+// source's transport stack.
+func (bs *BaseScraper) newHTTPClient(timeout time.Duration, userAgent string, settings config.HTTPSettings) (*http.Client, error) {
+	rt, err := bs.httpTransport(userAgent, settings)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Timeout: timeout, Transport: rt}, nil
+}
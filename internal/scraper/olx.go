@@ -0,0 +1,264 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/notifier"
+	"rent-watcher/internal/storage"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// OLXScraper scrapes OLX real estate searches by reading the __NEXT_DATA__
+// JSON the listing pages embed, which includes owner-listed rentals.
+type OLXScraper struct {
+	BaseScraper
+	Config OLXConfig
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type OLXConfig config.OLXConfig
+
+type olxNextData struct {
+	Props struct {
+		PageProps struct {
+			Ads []olxAd `json:"ads"`
+		} `json:"pageProps"`
+	} `json:"props"`
+}
+
+type olxAd struct {
+	ListID          int64  `json:"listId"`
+	Subject         string `json:"subject"`
+	PriceValue      string `json:"priceValue"`
+	URL             string `json:"url"`
+	Thumbnail       string `json:"thumbnail"`
+	ProfessionalAd  bool   `json:"professionalAd"`
+	LocationDetails struct {
+		Neighbourhood string `json:"neighbourhood"`
+		Municipality  string `json:"municipality"`
+		UF            string `json:"uf"`
+	} `json:"locationDetails"`
+	Images []struct {
+		Original string `json:"original"`
+	} `json:"images"`
+	Properties []struct {
+		Name  string `json:"name"`
+		Label string `json:"label"`
+		Value string `json:"value"`
+	} `json:"properties"`
+}
+
+func NewOLXScraper(config OLXConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) (*OLXScraper, error) {
+	if config.Name == "" || config.SearchURL == "" {
+		return nil, fmt.Errorf("olx scraper requires name and search_url")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OLXScraper{
+		BaseScraper: BaseScraper{
+			Storage:             storage,
+			Notifier:            notifier,
+			GeolocationProvider: geoProvider,
+			DestinationLat:      destinationLat,
+			DestinationLng:      destinationLng,
+			listingPage:         everyPage,
+		},
+		Config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+func (ox *OLXScraper) Scrape(ctx context.Context) (err error) {
+	ox.mu.Lock()
+	ox.ctx, ox.cancel = context.WithCancel(ctx)
+	ox.mu.Unlock()
+	ox.resetStats(ox.ctx, ox.Config.Name)
+	ox.beginRun(ox.Config.Name, storage.RunModeFull)
+	defer func() { ox.finishRun(err) }()
+	defer func() {
+		if err == nil {
+			err = ox.checkDrift(ox.Config.Drift)
+		}
+	}()
+
+	c, err := ox.newCollector(ox.Config.UserAgent, ox.Config.HTTPSettings)
+	if err != nil {
+		return err
+	}
+
+	pages := newPageTracker()
+	c.OnHTML("script#__NEXT_DATA__", func(e *colly.HTMLElement) {
+		ox.processNextData(e.Text, pages)
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		log.Printf("[%s] Request URL: %s failed with response: %v\nError: %v\n",
+			ox.Config.Name, r.Request.URL, r, err)
+	})
+
+	limit := maxPages(ox.Config.MaxPages)
+	firstPage := true
+	for page := 1; page <= limit; page++ {
+		select {
+		case <-ox.ctx.Done():
+			return ox.ctx.Err()
+		default:
+		}
+
+		pageURL, err := ox.pageURL(page)
+		if err != nil {
+			return err
+		}
+
+		pages.startPage()
+		log.Printf("[%s] Visiting page %d: %s\n", ox.Config.Name, page, pageURL)
+		if err := c.Visit(pageURL); err != nil {
+			if ox.ctx.Err() != nil {
+				return ox.ctx.Err()
+			}
+			log.Printf("[%s] Failed to visit page %d: %v\n", ox.Config.Name, page, err)
+			ox.recordFailure(pageURL, err)
+			continue
+		}
+		ox.updateStats(func(s *RunStats) {
+			s.PagesVisited++
+			if firstPage {
+				s.EmptyFirstPage = s.Cards == 0
+			}
+		})
+		firstPage = false
+
+		if reason := pages.stopReason(false); reason != "" {
+			log.Printf("[%s] Stopping pagination after page %d: %s\n", ox.Config.Name, page, reason)
+			ox.updateStats(func(s *RunStats) { s.StopReason = reason })
+			return nil
+		}
+	}
+
+	ox.updateStats(func(s *RunStats) { s.StopReason = "max pages reached" })
+	return nil
+}
+
+func (ox *OLXScraper) pageURL(page int) (string, error) {
+	u, err := url.Parse(ox.Config.SearchURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse search URL: %w", err)
+	}
+	q := u.Query()
+	if page > 1 {
+		q.Set("o", strconv.Itoa(page))
+	} else {
+		q.Del("o")
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// processNextData handles one page's __NEXT_DATA__ payload, recording the ads
+// found on it in pages.
+func (ox *OLXScraper) processNextData(payload string, pages *pageTracker) {
+	var data olxNextData
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		log.Printf("[%s] Error unmarshalling __NEXT_DATA__: %v\n", ox.Config.Name, err)
+		ox.updateStats(func(s *RunStats) { s.ParseFailures++ })
+		return
+	}
+
+	for _, ad := range data.Props.PageProps.Ads {
+		// Advertising slots are mixed with the ads and have no listId.
+		if ad.ListID == 0 {
+			continue
+		}
+		ox.updateStats(func(s *RunStats) { s.Cards++ })
+		if !pages.addID(strconv.FormatInt(ad.ListID, 10)) {
+			// Already processed on this or an earlier page.
+			continue
+		}
+
+		rawData, err := json.Marshal(ad)
+		if err != nil {
+			log.Printf("[%s] Error encoding ad %d: %v\n", ox.Config.Name, ad.ListID, err)
+		}
+
+		property := ox.mapAd(ad)
+		if !ox.hasRequiredFields(property, ox.Config.Drift.RequiredFields) {
+			continue
+		}
+		if err := ox.ProcessProperty(ox.ctx, property, string(rawData)); err != nil {
+			log.Printf("[%s] Error processing property: %v\n", ox.Config.Name, err)
+		}
+	}
+}
+
+func (ox *OLXScraper) mapAd(ad olxAd) *models.Property {
+	property := &models.Property{
+		ID:        fmt.Sprintf("%s:%d", ox.Config.Name, ad.ListID),
+		Source:    ox.Config.Name,
+		Operation: ox.operation(),
+		URL:       ad.URL,
+		Price:     cleanMoneyString(ad.PriceValue),
+		Bairro:    ad.LocationDetails.Neighbourhood,
+		Cidade:    ad.LocationDetails.Municipality,
+	}
+
+	for _, p := range ad.Properties {
+		value := strings.TrimSpace(p.Value)
+		switch p.Name {
+		case "condominio":
+			property.Condominio = cleanMoneyString(value)
+		case "iptu":
+			property.IPTU = cleanMoneyString(value)
+		case "size":
+			property.Metragem = strings.TrimSpace(strings.TrimSuffix(value, "m²"))
+		case "rooms":
+			property.Quartos = value
+		case "bathrooms":
+			property.Banheiros = value
+		case "garage_spaces":
+			property.Garagens = value
+		case "real_estate_type":
+			property.TipoImovel = value
+		default:
+			label := p.Label
+			if label == "" {
+				label = p.Name
+			}
+			property.SetAttribute(ox.Config.Name, label, value)
+		}
+	}
+	property.SetAttribute(ox.Config.Name, "titulo", ad.Subject)
+	property.SetAttribute(ox.Config.Name, "direto_com_proprietario", strconv.FormatBool(!ad.ProfessionalAd))
+
+	for _, image := range ad.Images {
+		if image.Original != "" {
+			property.Photos = append(property.Photos, image.Original)
+		}
+	}
+	property.FirstPhoto = ad.Thumbnail
+	if len(property.Photos) > 0 {
+		property.FirstPhoto = property.Photos[0]
+	}
+
+	return property
+}
+
+func (ox *OLXScraper) operation() string {
+	if ox.Config.Operation != "" {
+		return ox.Config.Operation
+	}
+	if strings.Contains(ox.Config.SearchURL, "/venda") {
+		return models.OperationSale
+	}
+	return models.OperationRent
+}
@@ -0,0 +1,102 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"rent-watcher/internal/models"
+	"sync"
+	"testing"
+)
+
+func TestOLXScraper(t *testing.T) {
+	var mu sync.Mutex
+	var pages []string
+	srv := serveFixtures(t, "text/html; charset=utf-8", func(r *http.Request) string {
+		page := r.URL.Query().Get("o")
+		mu.Lock()
+		pages = append(pages, page)
+		mu.Unlock()
+		if r.URL.Path != "/imoveis/aluguel/estado-pi" || r.URL.Query().Get("pe") != "2500" {
+			return ""
+		}
+		switch page {
+		case "":
+			return "olx_page1.html"
+		case "2":
+			return "olx_owner.html"
+		}
+		return "olx_empty.html"
+	})
+
+	notifier := &testNotifier{}
+	ox, err := NewOLXScraper(OLXConfig{
+		Name:      "olx",
+		SearchURL: srv.URL + "/imoveis/aluguel/estado-pi?pe=2500",
+		MaxPages:  10,
+	}, 0, 0, newTestStorage(t), notifier, nil)
+	if err != nil {
+		t.Fatalf("NewOLXScraper: %v", err)
+	}
+	if err := ox.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	if len(pages) != 3 || pages[0] != "" || pages[1] != "2" || pages[2] != "3" {
+		t.Errorf("visited pages = %q, want [\"\" 2 3]", pages)
+	}
+	stats := ox.Stats()
+	if stats.PagesVisited != 3 || stats.StopReason != "empty page" {
+		t.Errorf("stats = %s, want 3 pages stopped by the empty page", stats)
+	}
+	// The advertising slot on the first page is not a listing.
+	if stats.Cards != 3 || stats.PropertiesProcessed != 3 {
+		t.Errorf("cards/properties = %d/%d, want 3/3", stats.Cards, stats.PropertiesProcessed)
+	}
+
+	notified := notifier.notified()
+	agency := notified["olx:1298765401"]
+	if agency == nil {
+		t.Fatalf("listing olx:1298765401 not notified: %v", notified)
+	}
+	for _, tc := range []struct{ field, got, want string }{
+		{"price", agency.Price, "1.500"},
+		{"condominio", agency.Condominio, "300"},
+		{"iptu", agency.IPTU, "50"},
+		{"total_price", agency.TotalPrice, "1800.00"},
+		{"metragem", agency.Metragem, "65"},
+		{"quartos", agency.Quartos, "2"},
+		{"tipo_imovel", agency.TipoImovel, "Apartamento padrão"},
+		{"bairro", agency.Bairro, "Centro"},
+		{"operation", agency.Operation, models.OperationRent},
+		{"direto_com_proprietario", agency.Attributes["direto_com_proprietario"], "false"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+	if got := notified["olx:1298765402"].Attributes["mobiliado"]; got != "true" {
+		t.Errorf("mobiliado = %q, want true", got)
+	}
+
+	owner := notified["olx:1298765403"]
+	if owner == nil {
+		t.Fatalf("owner listing not notified")
+	}
+	if got := owner.Attributes["direto_com_proprietario"]; got != "true" {
+		t.Errorf("owner listing direto_com_proprietario = %q, want true", got)
+	}
+	if got := owner.Attributes["aceita_pets"]; got != "true" {
+		t.Errorf("owner listing aceita_pets = %q, want true", got)
+	}
+	if owner.Condominio != "" || owner.TotalPrice != "1.200" {
+		t.Errorf("owner listing condominio/total = %q/%q, want empty and the rent", owner.Condominio, owner.TotalPrice)
+	}
+
+	stored, err := ox.Storage.GetProperty("olx:1298765403")
+	if err != nil {
+		t.Fatalf("owner listing not stored: %v", err)
+	}
+	if stored.Attributes["direto_com_proprietario"] != "true" {
+		t.Errorf("stored attributes = %v", stored.Attributes)
+	}
+}
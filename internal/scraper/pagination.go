@@ -0,0 +1,93 @@
+package scraper
+
+import "fmt"
+
+// defaultMaxPages caps pagination when a source does not configure max_pages.
+const defaultMaxPages = 50
+
+// pageTracker decides when pagination should stop: after an empty page, after
+// a page whose listings were all seen earlier in the run (sites often repeat
+// the last page for out-of-range page numbers), or when the page had no
+// "next" link. In incremental runs it also stops once listings already in
+// storage are reached.
+type pageTracker struct {
+	seen        map[string]bool
+	pageIDs     int
+	pageNewIDs  int
+	pageKnown   int
+	knownStreak int
+	hasNext     bool
+}
+
+func newPageTracker() *pageTracker {
+	return &pageTracker{seen: make(map[string]bool)}
+}
+
+func (pt *pageTracker) startPage() {
+	pt.pageIDs = 0
+	pt.pageNewIDs = 0
+	pt.pageKnown = 0
+	pt.hasNext = false
+}
+
+// addID records a listing on the current page and reports whether it is the
+// first time the run sees it.
+func (pt *pageTracker) addID(id string) bool {
+	pt.pageIDs++
+	if pt.seen[id] {
+		return false
+	}
+	pt.seen[id] = true
+	pt.pageNewIDs++
+	return true
+}
+
+// addKnown records whether the last added listing was already in storage.
+// knownStreak counts consecutive known listings across pages.
+func (pt *pageTracker) addKnown(known bool) {
+	if !known {
+		pt.knownStreak = 0
+		return
+	}
+	pt.pageKnown++
+	pt.knownStreak++
+}
+
+func (pt *pageTracker) markNext() {
+	pt.hasNext = true
+}
+
+// stopReason returns why pagination should stop after the current page, or ""
+// to continue. checkNext enables the "next" link check for sources that
+// configure one.
+func (pt *pageTracker) stopReason(checkNext bool) string {
+	switch {
+	case pt.pageIDs == 0:
+		return "empty page"
+	case pt.pageNewIDs == 0:
+		return "repeated page"
+	case checkNext && !pt.hasNext:
+		return "no next page"
+	}
+	return ""
+}
+
+// knownStopReason returns why an incremental run should stop after the
+// current page, or "" to continue. A streakLimit of zero disables the
+// consecutive known listings check.
+func (pt *pageTracker) knownStopReason(streakLimit int) string {
+	switch {
+	case pt.pageIDs > 0 && pt.pageKnown == pt.pageIDs:
+		return "only known listings"
+	case streakLimit > 0 && pt.knownStreak >= streakLimit:
+		return fmt.Sprintf("%d consecutive known listings", pt.knownStreak)
+	}
+	return ""
+}
+
+func maxPages(configured int) int {
+	if configured <= 0 {
+		return defaultMaxPages
+	}
+	return configured
+}
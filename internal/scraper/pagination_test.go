@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"rent-watcher/internal/config"
+	"strconv"
+	"testing"
+)
+
+func TestPageTrackerStopReason(t *testing.T) {
+	pt := newPageTracker()
+	for _, tc := range []struct {
+		name      string
+		ids       []string
+		hasNext   bool
+		checkNext bool
+		want      string
+	}{
+		{"new listings", []string{"1", "2"}, false, false, ""},
+		{"next link", []string{"3"}, true, true, ""},
+		{"missing next link", []string{"4"}, false, true, "no next page"},
+		{"partly repeated", []string{"4", "5"}, false, false, ""},
+		{"repeated", []string{"1", "5"}, false, false, "repeated page"},
+		{"empty", nil, true, true, "empty page"},
+	} {
+		pt.startPage()
+		for _, id := range tc.ids {
+			pt.addID(id)
+		}
+		if tc.hasNext {
+			pt.markNext()
+		}
+		if got := pt.stopReason(tc.checkNext); got != tc.want {
+			t.Errorf("%s: stopReason = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// A site that answers every page past the last with the last one stops on the
+// first repeated page instead of running to max_pages.
+func TestSelectorScraperStopsOnRepeatedPage(t *testing.T) {
+	var pages []int
+	srv := serveFixtures(t, "text/html; charset=utf-8", func(r *http.Request) string {
+		if r.URL.Path != "/alugar" {
+			return ""
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("pagina"))
+		pages = append(pages, page)
+		if page <= 1 {
+			return "selector_page1.html"
+		}
+		return "selector_page2.html"
+	})
+
+	ss, err := NewSelectorScraper(SelectorConfig{
+		Name:         "exemplo",
+		BaseURL:      srv.URL,
+		ListingURL:   "/alugar?pagina={page}",
+		Pagination:   config.PaginationConfig{MaxPages: 10},
+		CardSelector: ".imovel-card",
+		Fields:       map[string]config.FieldSelector{"id": {Attr: "data-id"}, "price": {Selector: ".preco", Regex: `R\$\s*([\d.,]+)`}},
+	}, 0, 0, newTestStorage(t), &testNotifier{}, nil)
+	if err != nil {
+		t.Fatalf("NewSelectorScraper: %v", err)
+	}
+	if err := ss.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	if len(pages) != 3 {
+		t.Errorf("visited pages %v, want 1 to 3", pages)
+	}
+	if stats := ss.Stats(); stats.StopReason != "repeated page" || stats.PropertiesProcessed != 3 {
+		t.Errorf("stats = %s, want 3 properties stopped by the repeated page", stats)
+	}
+}
@@ -0,0 +1,327 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/notifier"
+	"rent-watcher/internal/storage"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	quintoAndarDefaultPageSize = 36
+	quintoAndarRequestTimeout  = 30 * time.Second
+)
+
+// QuintoAndarScraper queries the QuintoAndar search JSON for a region. Its
+// listings carry the all-inclusive monthly cost, which is used as TotalPrice.
+type QuintoAndarScraper struct {
+	BaseScraper
+	Config     QuintoAndarConfig
+	HTTPClient *http.Client
+	mu         sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+type QuintoAndarConfig config.QuintoAndarConfig
+
+type quintoAndarCoordinate struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+type quintoAndarPriceRange struct {
+	CostType string `json:"costType"`
+	Range    struct {
+		Min int `json:"min,omitempty"`
+		Max int `json:"max,omitempty"`
+	} `json:"range"`
+}
+
+type quintoAndarSearchRequest struct {
+	Filters struct {
+		BusinessContext string `json:"businessContext"`
+		Location        struct {
+			Coordinate  quintoAndarCoordinate `json:"coordinate"`
+			CountryCode string                `json:"countryCode"`
+		} `json:"location"`
+		PriceRange []quintoAndarPriceRange `json:"priceRange"`
+	} `json:"filters"`
+	Sorting struct {
+		Criteria string `json:"criteria"`
+		Order    string `json:"order"`
+	} `json:"sorting"`
+	Pagination struct {
+		PageSize int `json:"pageSize"`
+		Offset   int `json:"offset"`
+	} `json:"pagination"`
+	Slug string `json:"slug"`
+}
+
+type quintoAndarSearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			ID     string           `json:"_id"`
+			Source quintoAndarHouse `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+type quintoAndarHouse struct {
+	ID                  string   `json:"id"`
+	Type                string   `json:"type"`
+	Rent                float64  `json:"rent"`
+	CondoFee            float64  `json:"condoFee"`
+	IPTU                float64  `json:"iptu"`
+	IPTUPlusCondominium float64  `json:"iptuPlusCondominium"`
+	Insurance           float64  `json:"insurance"`
+	ServiceFee          float64  `json:"serviceFee"`
+	TotalCost           float64  `json:"totalCost"`
+	Area                float64  `json:"area"`
+	Bedrooms            *int     `json:"bedrooms"`
+	Bathrooms           *int     `json:"bathrooms"`
+	Suites              *int     `json:"suites"`
+	ParkingSpots        *int     `json:"parkingSpots"`
+	Address             string   `json:"address"`
+	Neighbourhood       string   `json:"neighbourhood"`
+	City                string   `json:"city"`
+	CoverImage          string   `json:"coverImage"`
+	ImageList           []string `json:"imageList"`
+	Location            struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"location"`
+}
+
+func NewQuintoAndarScraper(config QuintoAndarConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) (*QuintoAndarScraper, error) {
+	if config.Name == "" || config.BaseURL == "" || config.Slug == "" {
+		return nil, fmt.Errorf("quintoandar scraper requires name, base_url and slug")
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = quintoAndarDefaultPageSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	qs := &QuintoAndarScraper{
+		BaseScraper: BaseScraper{
+			Storage:             storage,
+			Notifier:            notifier,
+			GeolocationProvider: geoProvider,
+			DestinationLat:      destinationLat,
+			DestinationLng:      destinationLng,
+		},
+		Config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	client, err := qs.newHTTPClient(quintoAndarRequestTimeout, config.UserAgent, config.HTTPSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	qs.HTTPClient = client
+	return qs, nil
+}
+
+func (qs *QuintoAndarScraper) Scrape(ctx context.Context) (err error) {
+	qs.mu.Lock()
+	qs.ctx, qs.cancel = context.WithCancel(ctx)
+	qs.mu.Unlock()
+	qs.resetStats(qs.ctx, qs.Config.Name)
+	qs.beginRun(qs.Config.Name, storage.RunModeFull)
+	defer func() { qs.finishRun(err) }()
+	defer func() {
+		if err == nil {
+			err = qs.checkDrift(qs.Config.Drift)
+		}
+	}()
+
+	limit := maxPages(qs.Config.MaxPages)
+	firstPage := true
+	for page := 1; page <= limit; page++ {
+		select {
+		case <-qs.ctx.Done():
+			return qs.ctx.Err()
+		default:
+		}
+
+		offset := (page - 1) * qs.Config.PageSize
+		log.Printf("[%s] Fetching page %d (offset=%d)\n", qs.Config.Name, page, offset)
+		response, err := qs.fetchPage(offset)
+		if err != nil {
+			if qs.ctx.Err() != nil {
+				return qs.ctx.Err()
+			}
+			log.Printf("[%s] Failed to fetch page %d: %v\n", qs.Config.Name, page, err)
+			qs.recordFailure(fmt.Sprintf("%s?offset=%d", qs.Config.BaseURL, offset), err)
+			continue
+		}
+
+		qs.updateStats(func(s *RunStats) {
+			s.PagesVisited++
+			s.Cards += len(response.Hits.Hits)
+			if firstPage {
+				s.EmptyFirstPage = s.Cards == 0
+			}
+		})
+		firstPage = false
+
+		for _, hit := range response.Hits.Hits {
+			house := hit.Source
+			if house.ID == "" {
+				house.ID = hit.ID
+			}
+			qs.processHouse(house)
+		}
+
+		hits := response.Hits.Hits
+		if len(hits) == 0 || offset+len(hits) >= response.Hits.Total.Value {
+			log.Printf("[%s] Reached the last page (%d)\n", qs.Config.Name, page)
+			qs.updateStats(func(s *RunStats) { s.StopReason = "last page" })
+			return nil
+		}
+	}
+
+	qs.updateStats(func(s *RunStats) { s.StopReason = "max pages reached" })
+	return nil
+}
+
+func (qs *QuintoAndarScraper) fetchPage(offset int) (*quintoAndarSearchResponse, error) {
+	var search quintoAndarSearchRequest
+	search.Filters.BusinessContext = "RENT"
+	search.Filters.Location.Coordinate = quintoAndarCoordinate{Lat: qs.Config.Lat, Lng: qs.Config.Lng}
+	search.Filters.Location.CountryCode = "BR"
+	priceRange := quintoAndarPriceRange{CostType: "TOTAL_COST"}
+	priceRange.Range.Min = qs.Config.PriceMin
+	priceRange.Range.Max = qs.Config.PriceMax
+	search.Filters.PriceRange = []quintoAndarPriceRange{priceRange}
+	search.Sorting.Criteria = "MOST_RECENT"
+	search.Sorting.Order = "DESC"
+	search.Pagination.PageSize = qs.Config.PageSize
+	search.Pagination.Offset = offset
+	search.Slug = qs.Config.Slug
+
+	body, err := json.Marshal(search)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(qs.ctx, http.MethodPost, qs.Config.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", qs.Config.UserAgent)
+
+	resp, err := qs.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result quintoAndarSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+func (qs *QuintoAndarScraper) processHouse(house quintoAndarHouse) {
+	rawData, err := json.Marshal(house)
+	if err != nil {
+		log.Printf("[%s] Error encoding house %s: %v\n", qs.Config.Name, house.ID, err)
+	}
+
+	property := qs.mapHouse(house)
+	if !qs.hasRequiredFields(property, qs.Config.Drift.RequiredFields) {
+		return
+	}
+
+	if err := qs.ProcessProperty(qs.ctx, property, string(rawData)); err != nil {
+		log.Printf("[%s] Error processing property: %v\n", qs.Config.Name, err)
+	}
+}
+
+func (qs *QuintoAndarScraper) mapHouse(house quintoAndarHouse) *models.Property {
+	property := &models.Property{
+		ID:         qs.Config.Name + ":" + house.ID,
+		Source:     qs.Config.Name,
+		Operation:  models.OperationRent,
+		URL:        strings.TrimSuffix(qs.Config.SiteURL, "/") + "/imovel/" + house.ID,
+		Price:      formatAmount(house.Rent),
+		Condominio: formatAmount(house.CondoFee),
+		IPTU:       formatAmount(house.IPTU),
+		TotalPrice: formatAmount(house.TotalCost),
+		Logradouro: house.Address,
+		Bairro:     house.Neighbourhood,
+		Cidade:     house.City,
+		Metragem:   formatAmount(house.Area),
+		Quartos:    formatCount(house.Bedrooms),
+		Banheiros:  formatCount(house.Bathrooms),
+		Suites:     formatCount(house.Suites),
+		Garagens:   formatCount(house.ParkingSpots),
+		TipoImovel: house.Type,
+		Latitude:   house.Location.Lat,
+		Longitude:  house.Location.Lon,
+	}
+
+	// Some regions only report condo fee and IPTU combined.
+	if property.Condominio == "" && property.IPTU == "" && house.IPTUPlusCondominium > 0 {
+		property.Condominio = formatAmount(house.IPTUPlusCondominium)
+	}
+	if house.Insurance > 0 {
+		property.SetAttribute(qs.Config.Name, "seguro_incendio", formatAmount(house.Insurance))
+	}
+	if house.ServiceFee > 0 {
+		property.SetAttribute(qs.Config.Name, "taxa_de_servico", formatAmount(house.ServiceFee))
+	}
+
+	images := house.ImageList
+	if len(images) == 0 && house.CoverImage != "" {
+		images = []string{house.CoverImage}
+	}
+	for _, image := range images {
+		if !strings.HasPrefix(image, "http") {
+			image = strings.TrimSuffix(qs.Config.ImageURL, "/") + "/" + image
+		}
+		property.Photos = append(property.Photos, image)
+	}
+	if len(property.Photos) > 0 {
+		property.FirstPhoto = property.Photos[0]
+	}
+
+	return property
+}
+
+// formatCount formats a room count, leaving counts the API omits empty.
+func formatCount(count *int) string {
+	if count == nil {
+		return ""
+	}
+	return strconv.Itoa(*count)
+}
+
+// formatAmount formats a numeric API value, leaving zero values empty.
+func formatAmount(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return formatNumber(value)
+}
@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestQuintoAndarScraper(t *testing.T) {
+	srv := serveFixtures(t, "application/json", func(r *http.Request) string {
+		var search quintoAndarSearchRequest
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&search) != nil {
+			return ""
+		}
+		if search.Slug != "teresina-pi-brasil" || search.Pagination.Offset != 0 || search.Filters.BusinessContext != "RENT" {
+			return ""
+		}
+		return "quintoandar_search.json"
+	})
+
+	notifier := &testNotifier{}
+	qs, err := NewQuintoAndarScraper(QuintoAndarConfig{
+		Name:     "quintoandar",
+		BaseURL:  srv.URL + "/house-listing-search/v2/search/list",
+		SiteURL:  "https://www.quintoandar.com.br",
+		ImageURL: "https://www.quintoandar.com.br/img/med",
+		Slug:     "teresina-pi-brasil",
+		MaxPages: 3,
+	}, 0, 0, newTestStorage(t), notifier, nil)
+	if err != nil {
+		t.Fatalf("NewQuintoAndarScraper: %v", err)
+	}
+	if err := qs.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	stats := qs.Stats()
+	if stats.PagesVisited != 1 || stats.PropertiesProcessed != 2 || stats.StopReason != "last page" {
+		t.Errorf("stats = %s", stats)
+	}
+
+	notified := notifier.notified()
+	apartment := notified["quintoandar:893456123"]
+	if apartment == nil {
+		t.Fatalf("listing quintoandar:893456123 not notified: %v", notified)
+	}
+	for _, tc := range []struct{ field, got, want string }{
+		{"price", apartment.Price, "1500"},
+		{"condominio", apartment.Condominio, "300"},
+		{"iptu", apartment.IPTU, "50"},
+		{"total_price", apartment.TotalPrice, "1995"},
+		{"quartos", apartment.Quartos, "2"},
+		{"suites", apartment.Suites, "0"},
+		{"seguro_incendio", apartment.Attributes["seguro_incendio"], "25"},
+		{"taxa_de_servico", apartment.Attributes["taxa_de_servico"], "120"},
+		{"url", apartment.URL, "https://www.quintoandar.com.br/imovel/893456123"},
+		{"first_foto", apartment.FirstPhoto, "https://www.quintoandar.com.br/img/med/original893456123-1.jpg"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+
+	// The second hit has no id in _source, only the combined condo fee and
+	// IPTU, and no room counts.
+	house := notified["quintoandar:893456124"]
+	if house == nil {
+		t.Fatalf("listing quintoandar:893456124 not notified: %v", notified)
+	}
+	if house.TotalPrice != "1288" || house.Condominio != "80" || house.IPTU != "" {
+		t.Errorf("total/condominio/iptu = %q/%q/%q, want 1288/80/empty", house.TotalPrice, house.Condominio, house.IPTU)
+	}
+	if house.Quartos != "" || house.Banheiros != "" || house.Suites != "" || house.Garagens != "" {
+		t.Errorf("unknown counts = %q/%q/%q/%q, want empty", house.Quartos, house.Banheiros, house.Suites, house.Garagens)
+	}
+	if house.FirstPhoto != "https://www.quintoandar.com.br/img/med/original893456124-capa.jpg" {
+		t.Errorf("first_foto = %q, want the cover image", house.FirstPhoto)
+	}
+}
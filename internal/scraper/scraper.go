@@ -3,13 +3,20 @@ package scraper
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
+	"net/url"
 	"rent-watcher/internal/models"
 	"rent-watcher/internal/notifier"
 	"rent-watcher/internal/storage"
+	"rent-watcher/internal/transport"
+	"strings"
+	"sync"
 )
 
 type Scraper interface {
 	Scrape(ctx context.Context) error
+	Stats() RunStats
 }
 
 type GeolocationProvider interface {
@@ -22,22 +29,44 @@ type BaseScraper struct {
 	GeolocationProvider GeolocationProvider
 	DestinationLat      float64
 	DestinationLng      float64
+
+	statsMu  sync.Mutex
+	stats    RunStats
+	runCtx   context.Context
+	run      *storage.ScrapeRun
+	retry    *transport.Retry
+	rotation *transport.Rotation
+
+	transportMu sync.Mutex
+	transport   http.RoundTripper
+	// listingPage picks the listing pages of the source, which change between
+	// runs and so are always revalidated by the cache.
+	listingPage func(u *url.URL) bool
 }
 
 func (bs *BaseScraper) ProcessProperty(ctx context.Context, property *models.Property, rawData string) error {
+	normalizeProperty(property)
+	if reasons := validateProperty(property); len(reasons) > 0 {
+		bs.rejectProperty(property, rawData, reasons)
+		return fmt.Errorf("%w %q: %s", ErrInvalidProperty, property.ID, strings.Join(reasons, "; "))
+	}
+	if property.TotalPrice == "" {
+		if err := calculateTotalPrice(property); err != nil {
+			return err
+		}
+	}
+	if property.IsSale() && property.PricePerM2 == "" {
+		calculatePricePerM2(property)
+	}
+
 	exists, err := bs.Storage.PropertyExists(property.ID)
 	if err != nil {
 		return fmt.Errorf("error checking if property exists: %w", err)
 	}
 
 	if !exists {
-		if bs.GeolocationProvider != nil {
-			distance, err := bs.GeolocationProvider.CalculateDistance(ctx, property, bs.DestinationLat, bs.DestinationLng)
-			if err != nil {
-				return fmt.Errorf("error calculating distance: %w", err)
-			}
-			property.DistanceMeters = distance
-		}
+		property.EnrichPending = false
+		bs.enrich(ctx, property)
 
 		if err := bs.Notifier.NotifyNewProperty(property); err != nil {
 			return fmt.Errorf("error notifying about new property: %w", err)
@@ -48,6 +77,10 @@ func (bs *BaseScraper) ProcessProperty(ctx context.Context, property *models.Pro
 			return fmt.Errorf("error fetching existing property: %w", err)
 		}
 		property.DistanceMeters = existingProperty.DistanceMeters
+		property.EnrichPending = existingProperty.EnrichPending
+		if property.EnrichPending {
+			bs.enrich(ctx, property)
+		}
 	}
 
 	err = bs.Storage.SaveOrUpdateProperty(property, rawData)
@@ -55,5 +88,95 @@ func (bs *BaseScraper) ProcessProperty(ctx context.Context, property *models.Pro
 		return fmt.Errorf("error saving or updating property: %w", err)
 	}
 
+	bs.updateStats(func(s *RunStats) { s.PropertiesProcessed++ })
+
+	return nil
+}
+
+// enrich sets the distance of property to the destination. Failing to
+// calculate it does not stop the property from being notified and saved: it is
+// flagged as pending instead, and enrichment is retried the next time a run
+// sees the property or by retryEnrichment at the end of the run.
+func (bs *BaseScraper) enrich(ctx context.Context, property *models.Property) {
+	if bs.GeolocationProvider == nil {
+		return
+	}
+
+	distance, err := bs.GeolocationProvider.CalculateDistance(ctx, property, bs.DestinationLat, bs.DestinationLng)
+	if err != nil {
+		log.Printf("Error calculating distance of property %s, retrying later: %v\n", property.ID, err)
+		property.EnrichPending = true
+		return
+	}
+	property.DistanceMeters = distance
+	property.EnrichPending = false
+}
+
+// retryEnrichment retries the distance of every property of the source left
+// pending, including those of earlier runs, and counts those still pending.
+func (bs *BaseScraper) retryEnrichment() {
+	ctx := bs.runContext()
+	if bs.GeolocationProvider == nil || ctx == nil || ctx.Err() != nil {
+		return
+	}
+	source := bs.Stats().Source
+
+	properties, err := bs.Storage.PendingEnrichment(source)
+	if err != nil {
+		log.Printf("[%s] Error loading properties pending enrichment: %v\n", source, err)
+		return
+	}
+
+	pending := 0
+	for i, property := range properties {
+		if ctx.Err() != nil {
+			pending += len(properties) - i
+			break
+		}
+		distance, err := bs.GeolocationProvider.CalculateDistance(ctx, property, bs.DestinationLat, bs.DestinationLng)
+		if err != nil {
+			log.Printf("[%s] Error calculating distance of property %s: %v\n", source, property.ID, err)
+			pending++
+			continue
+		}
+		if err := bs.Storage.SaveDistance(property.ID, distance); err != nil {
+			log.Printf("[%s] Error saving distance of property %s: %v\n", source, property.ID, err)
+			pending++
+		}
+	}
+	bs.updateStats(func(s *RunStats) { s.EnrichPending = pending })
+}
+
+// calculateTotalPrice sets TotalPrice to the rent plus the condo fee when both
+// are known. For sales it is the asking price.
+func calculateTotalPrice(property *models.Property) error {
+	property.TotalPrice = property.Price
+	if property.IsSale() || property.Price == "" || property.Condominio == "" {
+		return nil
+	}
+
+	price, ok := models.ParseNumeric(property.Price)
+	if !ok {
+		return fmt.Errorf("error parsing price %q", property.Price)
+	}
+	condominio, ok := models.ParseNumeric(property.Condominio)
+	if !ok {
+		return fmt.Errorf("error parsing condominio %q", property.Condominio)
+	}
+
+	property.TotalPrice = fmt.Sprintf("%.2f", price+condominio)
 	return nil
 }
+
+// calculatePricePerM2 sets PricePerM2 of a sale when its area is known.
+func calculatePricePerM2(property *models.Property) {
+	price, ok := models.ParseNumeric(property.Price)
+	if !ok {
+		return
+	}
+	area, ok := models.ParseNumeric(property.Metragem)
+	if !ok || area <= 0 {
+		return
+	}
+	property.PricePerM2 = fmt.Sprintf("%.2f", price/area)
+}
@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"rent-watcher/internal/models"
+	"sync"
+	"testing"
+)
+
+// flakyGeolocation fails the first failures calculations of each property,
+// then returns 1500 m.
+type flakyGeolocation struct {
+	mu       sync.Mutex
+	failures int
+	calls    map[string]int
+}
+
+func (g *flakyGeolocation) CalculateDistance(ctx context.Context, property *models.Property, destLat, destLng float64) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls[property.ID]++
+	if g.calls[property.ID] <= g.failures {
+		return 0, errors.New("quota exceeded")
+	}
+	return 1500, nil
+}
+
+func TestPendingEnrichment(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		failures     int
+		wantPending  bool
+		wantDistance int
+		wantStats    int
+	}{
+		{name: "retried at the end of the run", failures: 1, wantDistance: 1500},
+		{name: "still failing", failures: 3, wantPending: true, wantStats: 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseURL := serveArantes(t)
+			store := newTestStorage(t)
+			as, notifier := newTestArantesScraper(t, baseURL, store, ArantesConfig{})
+			geo := &flakyGeolocation{failures: tc.failures, calls: make(map[string]int)}
+			as.GeolocationProvider = geo
+			if err := as.Scrape(context.Background()); err != nil {
+				t.Fatalf("Scrape: %v", err)
+			}
+
+			// The failed calculation does not hold back the notification.
+			notified := notifier.notified()["1001"]
+			if notified == nil {
+				t.Fatalf("property 1001 not notified: %v", notifier.notified())
+			}
+			if !notified.EnrichPending || notified.DistanceMeters != 0 {
+				t.Errorf("notified pending=%v distance=%d, want a pending property without distance", notified.EnrichPending, notified.DistanceMeters)
+			}
+
+			stored, err := store.GetProperty("1001")
+			if err != nil {
+				t.Fatalf("GetProperty: %v", err)
+			}
+			if stored.EnrichPending != tc.wantPending || stored.DistanceMeters != tc.wantDistance {
+				t.Errorf("stored pending=%v distance=%d, want pending=%v distance=%d", stored.EnrichPending, stored.DistanceMeters, tc.wantPending, tc.wantDistance)
+			}
+			if stats := as.Stats(); stats.EnrichPending != tc.wantStats {
+				t.Errorf("stats = %s, want %d properties pending enrichment", stats, tc.wantStats)
+			}
+			geo.mu.Lock()
+			calls := geo.calls["1001"]
+			geo.mu.Unlock()
+			if calls != 2 {
+				t.Errorf("distance of 1001 calculated %d times, want once while scraping and once at the end", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/notifier"
+	"rent-watcher/internal/storage"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+)
+
+// SelectorScraper scrapes agency sites described entirely by CSS selectors in
+// the configuration, so new agencies can be added without writing Go.
+type SelectorScraper struct {
+	BaseScraper
+	Config SelectorConfig
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	regex  map[string]*regexp.Regexp
+	pages  *pageTracker
+}
+
+type SelectorConfig config.SelectorScraperConfig
+
+// propertySetters maps configured field names onto models.Property. Fields not
+// listed here are stored as generic attributes.
+var propertySetters = map[string]func(p *models.Property, v string){
+	"id":          func(p *models.Property, v string) { p.ID = v },
+	"url":         func(p *models.Property, v string) { p.URL = v },
+	"first_photo": func(p *models.Property, v string) { p.FirstPhoto = v },
+	"price":       func(p *models.Property, v string) { p.Price = cleanMoneyString(v) },
+	"logradouro":  func(p *models.Property, v string) { p.Logradouro = v },
+	"bairro":      func(p *models.Property, v string) { p.Bairro = v },
+	"cidade":      func(p *models.Property, v string) { p.Cidade = v },
+	"metragem":    func(p *models.Property, v string) { p.Metragem = v },
+	"quartos":     func(p *models.Property, v string) { p.Quartos = v },
+	"banheiros":   func(p *models.Property, v string) { p.Banheiros = v },
+	"suites":      func(p *models.Property, v string) { p.Suites = v },
+	"garagens":    func(p *models.Property, v string) { p.Garagens = v },
+	"tipo_imovel": func(p *models.Property, v string) { p.TipoImovel = v },
+	"condominio":  func(p *models.Property, v string) { p.Condominio = cleanMoneyString(v) },
+}
+
+func NewSelectorScraper(cfg SelectorConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) (*SelectorScraper, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("selector scraper requires a name")
+	}
+	if cfg.ListingURL == "" || cfg.CardSelector == "" {
+		return nil, fmt.Errorf("selector scraper %s requires listing_url and card_selector", cfg.Name)
+	}
+
+	regexes := make(map[string]*regexp.Regexp)
+	for _, fields := range []map[string]config.FieldSelector{cfg.Fields, cfg.Details.Fields} {
+		for name, field := range fields {
+			if field.Regex == "" {
+				continue
+			}
+			re, err := regexp.Compile(field.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex for field %s of %s: %w", name, cfg.Name, err)
+			}
+			regexes[field.Regex] = re
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ss := &SelectorScraper{
+		BaseScraper: BaseScraper{
+			Storage:             storage,
+			Notifier:            notifier,
+			GeolocationProvider: geoProvider,
+			DestinationLat:      destinationLat,
+			DestinationLng:      destinationLng,
+		},
+		Config: cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		regex:  regexes,
+	}
+	ss.listingPage = ss.isListingPage
+	return ss, nil
+}
+
+func (ss *SelectorScraper) Scrape(ctx context.Context) (err error) {
+	ss.mu.Lock()
+	ss.ctx, ss.cancel = context.WithCancel(ctx)
+	ss.pages = newPageTracker()
+	ss.mu.Unlock()
+	ss.resetStats(ss.ctx, ss.Config.Name)
+	ss.beginRun(ss.Config.Name, storage.RunModeFull)
+	defer func() { ss.finishRun(err) }()
+	defer func() {
+		if err == nil {
+			err = ss.checkDrift(ss.Config.Drift)
+		}
+	}()
+
+	c, err := ss.newCollector(ss.Config.UserAgent, ss.Config.HTTPSettings)
+	if err != nil {
+		return err
+	}
+
+	var nextURL string
+	c.OnHTML(ss.Config.CardSelector, func(e *colly.HTMLElement) {
+		ss.processCard(e, c)
+	})
+	if ss.Config.Pagination.NextSelector != "" {
+		c.OnHTML(ss.Config.Pagination.NextSelector, func(e *colly.HTMLElement) {
+			nextURL = e.Request.AbsoluteURL(e.Attr("href"))
+			if nextURL != "" {
+				ss.pages.markNext()
+			}
+		})
+	}
+	c.OnError(func(r *colly.Response, err error) {
+		log.Printf("[%s] Request URL: %s failed with response: %v\nError: %v\n",
+			ss.Config.Name, r.Request.URL, r, err)
+	})
+
+	return ss.scrapePagination(c, &nextURL)
+}
+
+func (ss *SelectorScraper) scrapePagination(c *colly.Collector, nextURL *string) error {
+	page := ss.Config.Pagination.FirstPage
+	if page == 0 {
+		page = 1
+	}
+	pageURL := ss.listingURL(page)
+	followNext := ss.Config.Pagination.NextSelector != ""
+
+	firstPage := true
+	limit := maxPages(ss.Config.Pagination.MaxPages)
+	for visited := 0; visited < limit; visited++ {
+		select {
+		case <-ss.ctx.Done():
+			return ss.ctx.Err()
+		default:
+		}
+
+		*nextURL = ""
+		ss.pages.startPage()
+		log.Printf("[%s] Visiting page %d: %s\n", ss.Config.Name, page, pageURL)
+		if err := c.Visit(pageURL); err != nil {
+			if ss.ctx.Err() != nil {
+				return ss.ctx.Err()
+			}
+			log.Printf("[%s] Failed to visit page %d: %v\n", ss.Config.Name, page, err)
+			ss.recordFailure(pageURL, err)
+			if followNext {
+				return nil
+			}
+		} else {
+			ss.updateStats(func(s *RunStats) {
+				s.PagesVisited++
+				if firstPage {
+					s.EmptyFirstPage = s.Cards == 0
+				}
+			})
+			firstPage = false
+			if reason := ss.pages.stopReason(followNext); reason != "" {
+				log.Printf("[%s] Stopping pagination after page %d: %s\n", ss.Config.Name, page, reason)
+				ss.updateStats(func(s *RunStats) { s.StopReason = reason })
+				return nil
+			}
+		}
+
+		page++
+		if followNext {
+			pageURL = *nextURL
+		} else {
+			pageURL = ss.listingURL(page)
+		}
+	}
+
+	ss.updateStats(func(s *RunStats) { s.StopReason = "max pages reached" })
+	return nil
+}
+
+func (ss *SelectorScraper) listingURL(page int) string {
+	return ss.absoluteURL(strings.ReplaceAll(ss.Config.ListingURL, "{page}", strconv.Itoa(page)))
+}
+
+// isListingPage reports whether u is a page of the listing: the path of
+// ListingURL, or any path starting like it when {page} is part of the path.
+func (ss *SelectorScraper) isListingPage(u *url.URL) bool {
+	listing, err := url.Parse(ss.absoluteURL(ss.Config.ListingURL))
+	if err != nil || !strings.EqualFold(u.Host, listing.Host) {
+		return false
+	}
+	if i := strings.Index(listing.Path, "{page}"); i >= 0 {
+		return strings.HasPrefix(u.Path, listing.Path[:i])
+	}
+	return strings.TrimSuffix(u.Path, "/") == strings.TrimSuffix(listing.Path, "/")
+}
+
+func (ss *SelectorScraper) absoluteURL(u string) string {
+	if u == "" || strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+	base, err := url.Parse(ss.Config.BaseURL)
+	if err != nil {
+		return u
+	}
+	ref, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func (ss *SelectorScraper) processCard(e *colly.HTMLElement, c *colly.Collector) {
+	property := &models.Property{Source: ss.Config.Name, Operation: ss.Config.Operation}
+	ss.applyFields(e, property, ss.Config.Fields)
+	ss.updateStats(func(s *RunStats) { s.Cards++ })
+
+	if property.ID == "" {
+		log.Printf("[%s] Skipping card without id on %s\n", ss.Config.Name, e.Request.URL)
+		ss.updateStats(func(s *RunStats) { s.MissingFields++ })
+		rawData, _ := goquery.OuterHtml(e.DOM)
+		ss.quarantine(property, rawData, []string{"missing id"})
+		return
+	}
+	if !ss.pages.addID(property.ID) {
+		// Already processed on this or an earlier page.
+		return
+	}
+
+	detailsURL := property.URL
+	if detailsURL == "" && ss.Config.Details.URLTemplate != "" {
+		detailsURL = strings.ReplaceAll(ss.Config.Details.URLTemplate, "{id}", property.ID)
+	}
+	property.URL = ss.absoluteURL(detailsURL)
+	property.FirstPhoto = ss.absoluteURL(property.FirstPhoto)
+
+	if property.URL != "" && (len(ss.Config.Details.Fields) > 0 || ss.Config.Details.AttributeRows != "") {
+		ss.scrapeDetails(c, property)
+	}
+
+	rawData, err := goquery.OuterHtml(e.DOM)
+	if err != nil {
+		log.Printf("[%s] Error reading card HTML: %v\n", ss.Config.Name, err)
+	}
+	property.ID = ss.Config.Name + ":" + property.ID
+
+	if !ss.hasRequiredFields(property, ss.Config.Drift.RequiredFields) {
+		return
+	}
+
+	if err := ss.ProcessProperty(ss.ctx, property, rawData); err != nil {
+		log.Printf("[%s] Error processing property: %v\n", ss.Config.Name, err)
+	}
+}
+
+func (ss *SelectorScraper) scrapeDetails(c *colly.Collector, property *models.Property) {
+	detailsCollector := c.Clone()
+
+	detailsCollector.OnHTML("html", func(e *colly.HTMLElement) {
+		ss.applyFields(e, property, ss.Config.Details.Fields)
+		FillMissingFields(property, ExtractStructuredData(e.DOM))
+	})
+
+	if ss.Config.Details.AttributeRows != "" {
+		detailsCollector.OnHTML(ss.Config.Details.AttributeRows, func(e *colly.HTMLElement) {
+			label := strings.TrimSpace(e.ChildText(ss.Config.Details.AttributeLabel))
+			value := strings.TrimSpace(e.ChildText(ss.Config.Details.AttributeValue))
+			if label == "" {
+				return
+			}
+			property.SetAttribute(ss.Config.Name, label, value)
+			if set, ok := propertySetters[models.NormalizeAttributeKey(ss.Config.Name, label)]; ok && value != "" {
+				set(property, value)
+			}
+		})
+	}
+
+	if err := detailsCollector.Visit(property.URL); err != nil {
+		log.Printf("[%s] Error visiting details page for property %s: %v\n", ss.Config.Name, property.ID, err)
+		ss.recordFailure(property.URL, err)
+	}
+}
+
+func (ss *SelectorScraper) applyFields(e *colly.HTMLElement, property *models.Property, fields map[string]config.FieldSelector) {
+	for name, field := range fields {
+		value := ss.extractField(e, field)
+		if value == "" {
+			continue
+		}
+
+		if set, ok := propertySetters[name]; ok {
+			set(property, value)
+		} else {
+			property.SetAttribute(ss.Config.Name, name, value)
+		}
+	}
+}
+
+func (ss *SelectorScraper) extractField(e *colly.HTMLElement, field config.FieldSelector) string {
+	var value string
+	switch {
+	case field.Selector == "" && field.Attr != "":
+		value = e.Attr(field.Attr)
+	case field.Selector == "":
+		value = e.Text
+	case field.Attr != "":
+		value = e.ChildAttr(field.Selector, field.Attr)
+	default:
+		value = e.ChildText(field.Selector)
+	}
+	value = strings.TrimSpace(value)
+
+	if re := ss.regex[field.Regex]; re != nil {
+		m := re.FindStringSubmatch(value)
+		switch {
+		case m == nil:
+			value = ""
+		case len(m) > 1:
+			value = m[1]
+		default:
+			value = m[0]
+		}
+	}
+
+	return strings.TrimSpace(value)
+}
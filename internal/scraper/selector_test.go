@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"rent-watcher/internal/config"
+	"sync"
+	"testing"
+)
+
+func TestSelectorScraper(t *testing.T) {
+	var mu sync.Mutex
+	var visited []string
+	srv := serveFixtures(t, "text/html; charset=utf-8", func(r *http.Request) string {
+		mu.Lock()
+		visited = append(visited, r.URL.RequestURI())
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/alugar" && r.URL.Query().Get("pagina") == "1":
+			return "selector_page1.html"
+		case r.URL.Path == "/alugar" && r.URL.Query().Get("pagina") == "2":
+			return "selector_page2.html"
+		case r.URL.Path == "/imovel/101":
+			return "selector_details.html"
+		}
+		return ""
+	})
+
+	notifier := &testNotifier{}
+	ss, err := NewSelectorScraper(SelectorConfig{
+		Name:         "exemplo",
+		BaseURL:      srv.URL,
+		ListingURL:   "/alugar?pagina={page}",
+		Pagination:   config.PaginationConfig{MaxPages: 5, NextSelector: "a.proxima"},
+		CardSelector: ".imovel-card",
+		Fields: map[string]config.FieldSelector{
+			"id":          {Attr: "data-id"},
+			"url":         {Selector: "a.link", Attr: "href"},
+			"first_photo": {Selector: "img", Attr: "src"},
+			"price":       {Selector: ".preco", Regex: `R\$\s*([\d.,]+)`},
+			"bairro":      {Selector: ".bairro"},
+			"quartos":     {Selector: ".quartos", Regex: `\d+`},
+		},
+		Details: config.DetailsConfig{
+			Fields:         map[string]config.FieldSelector{"logradouro": {Selector: "h1.endereco"}},
+			AttributeRows:  "table.caracteristicas tr",
+			AttributeLabel: "th",
+			AttributeValue: "td",
+		},
+	}, 0, 0, newTestStorage(t), notifier, nil)
+	if err != nil {
+		t.Fatalf("NewSelectorScraper: %v", err)
+	}
+	if err := ss.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	stats := ss.Stats()
+	if stats.PagesVisited != 2 || stats.StopReason != "no next page" {
+		t.Errorf("stats = %s, want 2 pages stopped by the missing next link", stats)
+	}
+	// The second page repeats listing 102, which is processed only once.
+	if stats.Cards != 4 || stats.PropertiesProcessed != 3 {
+		t.Errorf("cards/properties = %d/%d, want 4/3", stats.Cards, stats.PropertiesProcessed)
+	}
+
+	notified := notifier.notified()
+	if len(notified) != 3 {
+		t.Fatalf("notified %d properties, want 3: %v", len(notified), notified)
+	}
+	first := notified["exemplo:101"]
+	if first == nil {
+		t.Fatalf("property exemplo:101 not notified: %v", notified)
+	}
+	for _, tc := range []struct{ field, got, want string }{
+		{"url", first.URL, srv.URL + "/imovel/101"},
+		{"first_photo", first.FirstPhoto, srv.URL + "/fotos/101-1.jpg"},
+		{"price", first.Price, "1.500,00"},
+		{"bairro", first.Bairro, "Centro"},
+		{"quartos", first.Quartos, "2"},
+		{"logradouro", first.Logradouro, "Rua Areolino de Abreu, 1200"},
+		{"condominio", first.Condominio, "350,00"},
+		{"total_price", first.TotalPrice, "1850.00"},
+		{"aceita_pets", first.Attributes["aceita_pets"], "true"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+
+	details := 0
+	for _, uri := range visited {
+		if uri == "/imovel/102" {
+			details++
+		}
+	}
+	if details != 1 {
+		t.Errorf("details of the repeated listing fetched %d times, want 1 (requests: %v)", details, visited)
+	}
+
+	// Listings whose details page is missing are still notified from the card.
+	if got := notified["exemplo:103"]; got == nil || got.Bairro != "Dirceu Arcoverde" || got.Logradouro != "" {
+		t.Errorf("exemplo:103 = %+v, want the card fields only", got)
+	}
+}
@@ -0,0 +1,179 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"rent-watcher/internal/storage"
+	"strings"
+)
+
+// RunStats summarises a single Scrape run of one source.
+type RunStats struct {
+	Source              string
+	Mode                string
+	Resumed             bool
+	PagesVisited        int
+	PropertiesProcessed int
+	DetailsFetched      int
+	DetailsSkipped      int
+	Cards               int
+	ParseFailures       int
+	MissingFields       int
+	Rejected            int
+	EnrichPending       int
+	EmptyFirstPage      bool
+	Retries             int
+	CacheHits           int
+	CacheRevalidated    int
+	CacheMisses         int
+	Failures            []storage.RunFailure
+	StopReason          string
+}
+
+func (rs RunStats) String() string {
+	var parts []string
+	if rs.Mode != "" {
+		parts = append(parts, fmt.Sprintf("mode=%s", rs.Mode))
+	}
+	if rs.Resumed {
+		parts = append(parts, "resumed")
+	}
+	parts = append(parts,
+		fmt.Sprintf("pages=%d", rs.PagesVisited),
+		fmt.Sprintf("properties=%d", rs.PropertiesProcessed),
+	)
+	if rs.DetailsFetched > 0 || rs.DetailsSkipped > 0 {
+		parts = append(parts,
+			fmt.Sprintf("details_fetched=%d", rs.DetailsFetched),
+			fmt.Sprintf("details_skipped=%d", rs.DetailsSkipped))
+	}
+	if rs.ParseFailures > 0 || rs.MissingFields > 0 {
+		parts = append(parts, fmt.Sprintf("cards=%d parse_failures=%d missing_fields=%d",
+			rs.Cards, rs.ParseFailures, rs.MissingFields))
+	}
+	if lookups := rs.CacheHits + rs.CacheRevalidated + rs.CacheMisses; lookups > 0 {
+		parts = append(parts, fmt.Sprintf("cache_hits=%d/%d (%.0f%%) revalidated=%d",
+			rs.CacheHits+rs.CacheRevalidated, lookups,
+			100*float64(rs.CacheHits+rs.CacheRevalidated)/float64(lookups), rs.CacheRevalidated))
+	}
+	if rs.Rejected > 0 {
+		parts = append(parts, fmt.Sprintf("rejected=%d", rs.Rejected))
+	}
+	if rs.EnrichPending > 0 {
+		parts = append(parts, fmt.Sprintf("enrichment_pending=%d", rs.EnrichPending))
+	}
+	if rs.Retries > 0 {
+		parts = append(parts, fmt.Sprintf("retries=%d", rs.Retries))
+	}
+	if len(rs.Failures) > 0 {
+		parts = append(parts, fmt.Sprintf("failed_pages=%d", len(rs.Failures)))
+	}
+	if rs.StopReason != "" {
+		parts = append(parts, fmt.Sprintf("stopped=%q", rs.StopReason))
+	}
+	return fmt.Sprintf("[%s] %s", rs.Source, strings.Join(parts, " "))
+}
+
+// Stats returns a snapshot of the statistics of the current or last run.
+func (bs *BaseScraper) Stats() RunStats {
+	bs.statsMu.Lock()
+	defer bs.statsMu.Unlock()
+	stats := bs.stats
+	stats.Failures = append([]storage.RunFailure(nil), bs.stats.Failures...)
+	return stats
+}
+
+// resetStats starts the statistics of a run whose requests are bound to ctx,
+// and renews the per-run state of the transport.
+func (bs *BaseScraper) resetStats(ctx context.Context, source string) {
+	bs.statsMu.Lock()
+	defer bs.statsMu.Unlock()
+	bs.stats = RunStats{Source: source}
+	bs.runCtx = ctx
+	if bs.retry != nil {
+		bs.retry.Reset()
+	}
+	if bs.rotation != nil {
+		bs.rotation.Reset()
+	}
+}
+
+// runContext returns the context of the current run, or nil before the first.
+func (bs *BaseScraper) runContext() context.Context {
+	bs.statsMu.Lock()
+	defer bs.statsMu.Unlock()
+	return bs.runCtx
+}
+
+// runID returns the ID of the run being recorded, or 0 when there is none.
+func (bs *BaseScraper) runID() int64 {
+	bs.statsMu.Lock()
+	defer bs.statsMu.Unlock()
+	if bs.run == nil {
+		return 0
+	}
+	return bs.run.ID
+}
+
+// recordFailure notes a page that could not be fetched even after retries.
+// A run with failures finishes as partial.
+func (bs *BaseScraper) recordFailure(pageURL string, err error) {
+	bs.updateStats(func(s *RunStats) {
+		s.Failures = append(s.Failures, storage.RunFailure{URL: pageURL, Error: err.Error()})
+	})
+}
+
+func (bs *BaseScraper) updateStats(update func(*RunStats)) {
+	bs.statsMu.Lock()
+	defer bs.statsMu.Unlock()
+	update(&bs.stats)
+}
+
+// beginRun records the start of a run in storage. Failing to record it is
+// logged but does not prevent the run.
+func (bs *BaseScraper) beginRun(source, mode string) {
+	bs.updateStats(func(s *RunStats) { s.Mode = mode })
+
+	run, err := bs.Storage.StartRun(source, mode)
+	if err != nil {
+		log.Printf("[%s] Error recording run start: %v\n", source, err)
+		return
+	}
+	bs.statsMu.Lock()
+	bs.run = run
+	bs.statsMu.Unlock()
+}
+
+// finishRun retries pending enrichment and stores the outcome of the run
+// started by beginRun, using err as returned by Scrape.
+func (bs *BaseScraper) finishRun(err error) {
+	bs.retryEnrichment()
+
+	bs.statsMu.Lock()
+	run, stats := bs.run, bs.stats
+	bs.run = nil
+	bs.statsMu.Unlock()
+	if run == nil {
+		return
+	}
+
+	switch {
+	case err == nil && len(stats.Failures) > 0:
+		run.Status = storage.RunStatusPartial
+	case err == nil:
+		run.Status = storage.RunStatusCompleted
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		run.Status = storage.RunStatusCancelled
+	default:
+		run.Status = storage.RunStatusFailed
+	}
+	run.PagesVisited = stats.PagesVisited
+	run.PropertiesProcessed = stats.PropertiesProcessed
+	run.Failures = stats.Failures
+
+	if err := bs.Storage.FinishRun(run); err != nil {
+		log.Printf("[%s] Error recording run result: %v\n", run.Source, err)
+	}
+}
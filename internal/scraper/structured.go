@@ -0,0 +1,291 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"rent-watcher/internal/models"
+)
+
+// listingTypes are the schema.org types whose fields describe a listing.
+var listingTypes = map[string]bool{
+	"RealEstateListing":     true,
+	"Apartment":             true,
+	"House":                 true,
+	"SingleFamilyResidence": true,
+	"Residence":             true,
+	"Accommodation":         true,
+	"Room":                  true,
+	"Place":                 true,
+	"Offer":                 true,
+	"Product":               true,
+}
+
+// ExtractStructuredData maps the schema.org JSON-LD blocks and OpenGraph tags
+// of a page onto a property. Only fields present in the page are set.
+func ExtractStructuredData(doc *goquery.Selection) *models.Property {
+	property := &models.Property{}
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return
+		}
+		walkJSONLD(data, property)
+	})
+
+	extractOpenGraph(doc, property)
+	return property
+}
+
+// FillMissingFields copies every field of extracted that is still empty in property.
+func FillMissingFields(property, extracted *models.Property) {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+
+	fill(&property.URL, extracted.URL)
+	fill(&property.FirstPhoto, extracted.FirstPhoto)
+	fill(&property.Price, extracted.Price)
+	fill(&property.Logradouro, extracted.Logradouro)
+	fill(&property.Bairro, extracted.Bairro)
+	fill(&property.Cidade, extracted.Cidade)
+	fill(&property.Metragem, extracted.Metragem)
+	fill(&property.Quartos, extracted.Quartos)
+	fill(&property.Banheiros, extracted.Banheiros)
+	fill(&property.TipoImovel, extracted.TipoImovel)
+
+	if property.Latitude == 0 && property.Longitude == 0 {
+		property.Latitude = extracted.Latitude
+		property.Longitude = extracted.Longitude
+	}
+	if len(property.Photos) == 0 {
+		property.Photos = extracted.Photos
+	}
+	if property.FirstPhoto == "" && len(property.Photos) > 0 {
+		property.FirstPhoto = property.Photos[0]
+	}
+}
+
+func walkJSONLD(data interface{}, property *models.Property) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			walkJSONLD(item, property)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			walkJSONLD(graph, property)
+		}
+		if !hasListingType(v["@type"]) {
+			return
+		}
+
+		applyJSONLDNode(v, property)
+		for _, key := range []string{"mainEntity", "about", "itemOffered", "containsPlace"} {
+			if nested, ok := v[key]; ok {
+				walkJSONLD(nested, property)
+			}
+		}
+	}
+}
+
+func hasListingType(t interface{}) bool {
+	switch v := t.(type) {
+	case string:
+		return listingTypes[v]
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && listingTypes[s] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applyJSONLDNode(node map[string]interface{}, property *models.Property) {
+	setIfEmpty := func(dst *string, value string) {
+		if *dst == "" && value != "" {
+			*dst = value
+		}
+	}
+
+	if t, ok := node["@type"].(string); ok && t != "Offer" && t != "RealEstateListing" && t != "Product" {
+		setIfEmpty(&property.TipoImovel, t)
+	}
+	setIfEmpty(&property.URL, jsonString(node["url"]))
+	setIfEmpty(&property.Price, jsonNumber(node["price"]))
+
+	for _, offer := range jsonObjects(node["offers"]) {
+		setIfEmpty(&property.Price, jsonNumber(offer["price"]))
+		for _, spec := range jsonObjects(offer["priceSpecification"]) {
+			setIfEmpty(&property.Price, jsonNumber(spec["price"]))
+		}
+	}
+
+	for _, address := range jsonObjects(node["address"]) {
+		setIfEmpty(&property.Logradouro, jsonString(address["streetAddress"]))
+		setIfEmpty(&property.Cidade, jsonString(address["addressLocality"]))
+		setIfEmpty(&property.Bairro, jsonString(address["neighborhood"]))
+	}
+
+	for _, geo := range jsonObjects(node["geo"]) {
+		lat, latOK := jsonFloat(geo["latitude"])
+		lng, lngOK := jsonFloat(geo["longitude"])
+		if latOK && lngOK && property.Latitude == 0 && property.Longitude == 0 {
+			property.Latitude, property.Longitude = lat, lng
+		}
+	}
+
+	for _, key := range []string{"image", "photo"} {
+		for _, photo := range jsonImages(node[key]) {
+			property.Photos = appendUnique(property.Photos, photo)
+		}
+	}
+
+	setIfEmpty(&property.Quartos, jsonNumber(quantityValue(node["numberOfBedrooms"])))
+	setIfEmpty(&property.Quartos, jsonNumber(quantityValue(node["numberOfRooms"])))
+	setIfEmpty(&property.Banheiros, jsonNumber(quantityValue(node["numberOfBathroomsTotal"])))
+	setIfEmpty(&property.Banheiros, jsonNumber(quantityValue(node["numberOfFullBathrooms"])))
+	setIfEmpty(&property.Metragem, jsonNumber(quantityValue(node["floorSize"])))
+}
+
+func extractOpenGraph(doc *goquery.Selection, property *models.Property) {
+	meta := func(names ...string) string {
+		for _, name := range names {
+			content, ok := doc.Find(fmt.Sprintf(`meta[property="%s"], meta[name="%s"]`, name, name)).First().Attr("content")
+			if ok && strings.TrimSpace(content) != "" {
+				return strings.TrimSpace(content)
+			}
+		}
+		return ""
+	}
+
+	if property.URL == "" {
+		property.URL = meta("og:url")
+	}
+	if property.Price == "" {
+		if price, ok := models.ParseNumeric(meta("product:price:amount", "og:price:amount")); ok {
+			property.Price = formatNumber(price)
+		}
+	}
+	if property.Logradouro == "" {
+		property.Logradouro = meta("og:street-address", "place:street_address")
+	}
+	if property.Cidade == "" {
+		property.Cidade = meta("og:locality")
+	}
+	if property.Latitude == 0 && property.Longitude == 0 {
+		lat, latErr := strconv.ParseFloat(meta("place:location:latitude", "og:latitude"), 64)
+		lng, lngErr := strconv.ParseFloat(meta("place:location:longitude", "og:longitude"), 64)
+		if latErr == nil && lngErr == nil {
+			property.Latitude, property.Longitude = lat, lng
+		}
+	}
+
+	doc.Find(`meta[property="og:image"]`).Each(func(_ int, s *goquery.Selection) {
+		if content, ok := s.Attr("content"); ok && content != "" {
+			property.Photos = appendUnique(property.Photos, content)
+		}
+	})
+	if property.FirstPhoto == "" && len(property.Photos) > 0 {
+		property.FirstPhoto = property.Photos[0]
+	}
+}
+
+func jsonObjects(v interface{}) []map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{t}
+	case []interface{}:
+		var objects []map[string]interface{}
+		for _, item := range t {
+			if m, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, m)
+			}
+		}
+		return objects
+	}
+	return nil
+}
+
+func jsonImages(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case map[string]interface{}:
+		for _, key := range []string{"contentUrl", "url"} {
+			if s := jsonString(t[key]); s != "" {
+				return []string{s}
+			}
+		}
+	case []interface{}:
+		var images []string
+		for _, item := range t {
+			images = append(images, jsonImages(item)...)
+		}
+		return images
+	}
+	return nil
+}
+
+func quantityValue(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m["value"]
+	}
+	return v
+}
+
+func jsonString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+func jsonFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// jsonNumber formats a JSON-LD number, or a numeric string, the same way the
+// scrapers format computed prices.
+func jsonNumber(v interface{}) string {
+	switch t := v.(type) {
+	case float64:
+		return formatNumber(t)
+	case string:
+		if f, ok := models.ParseNumeric(t); ok {
+			return formatNumber(f)
+		}
+	}
+	return ""
+}
+
+func formatNumber(f float64) string {
+	if f == float64(int64(f)) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return fmt.Sprintf("%.2f", f)
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"rent-watcher/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func loadDocument(t *testing.T, html string) *goquery.Selection {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+	return doc.Selection
+}
+
+func TestExtractStructuredDataJSONLD(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "structured_listing.html"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	property := ExtractStructuredData(loadDocument(t, string(data)))
+
+	for _, tc := range []struct{ field, got, want string }{
+		{"url", property.URL, "https://www.exemplo-imoveis.com.br/imovel/55"},
+		{"price", property.Price, "2500"},
+		{"tipo_imovel", property.TipoImovel, "Apartment"},
+		{"quartos", property.Quartos, "3"},
+		{"banheiros", property.Banheiros, "2"},
+		{"metragem", property.Metragem, "92.50"},
+		{"logradouro", property.Logradouro, "Rua Jóquei Clube, 300"},
+		{"bairro", property.Bairro, "Jóquei"},
+		{"cidade", property.Cidade, "Teresina"},
+		{"first_photo", property.FirstPhoto, "https://img.exemplo-imoveis.com.br/55-1.jpg"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+	if property.Latitude != -5.0612 || property.Longitude != -42.7891 {
+		t.Errorf("location = %v,%v, want -5.0612,-42.7891", property.Latitude, property.Longitude)
+	}
+	// JSON-LD photos come first; OpenGraph only adds the ones missing.
+	wantPhotos := []string{
+		"https://img.exemplo-imoveis.com.br/55-1.jpg",
+		"https://img.exemplo-imoveis.com.br/55-2.jpg",
+		"https://img.exemplo-imoveis.com.br/og.jpg",
+	}
+	if strings.Join(property.Photos, " ") != strings.Join(wantPhotos, " ") {
+		t.Errorf("photos = %v, want %v", property.Photos, wantPhotos)
+	}
+}
+
+func TestExtractStructuredDataOpenGraph(t *testing.T) {
+	property := ExtractStructuredData(loadDocument(t, `<html><head>
+<meta property="og:url" content="https://www.exemplo-imoveis.com.br/imovel/77">
+<meta property="product:price:amount" content="1.250,50">
+<meta property="og:street-address" content="Av. Frei Serafim, 10">
+<meta property="place:location:latitude" content="-5.09">
+<meta property="place:location:longitude" content="-42.80">
+<meta property="og:image" content="https://img.exemplo-imoveis.com.br/77.jpg">
+<meta property="og:image" content="https://img.exemplo-imoveis.com.br/77.jpg">
+</head><body></body></html>`))
+
+	for _, tc := range []struct{ field, got, want string }{
+		{"url", property.URL, "https://www.exemplo-imoveis.com.br/imovel/77"},
+		{"price", property.Price, "1250.50"},
+		{"logradouro", property.Logradouro, "Av. Frei Serafim, 10"},
+		{"first_photo", property.FirstPhoto, "https://img.exemplo-imoveis.com.br/77.jpg"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+	if len(property.Photos) != 1 {
+		t.Errorf("photos = %v, want the repeated image once", property.Photos)
+	}
+	if property.Latitude != -5.09 || property.Longitude != -42.80 {
+		t.Errorf("location = %v,%v, want -5.09,-42.80", property.Latitude, property.Longitude)
+	}
+}
+
+func TestFillMissingFields(t *testing.T) {
+	property := &models.Property{Price: "1.800,00", Bairro: "Centro"}
+	FillMissingFields(property, &models.Property{
+		Price:     "2000",
+		Bairro:    "Fátima",
+		Quartos:   "2",
+		Latitude:  -5.1,
+		Longitude: -42.8,
+		Photos:    []string{"https://img.exemplo-imoveis.com.br/1.jpg"},
+	})
+
+	for _, tc := range []struct{ field, got, want string }{
+		{"price", property.Price, "1.800,00"},
+		{"bairro", property.Bairro, "Centro"},
+		{"quartos", property.Quartos, "2"},
+		{"first_photo", property.FirstPhoto, "https://img.exemplo-imoveis.com.br/1.jpg"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+	if property.Latitude != -5.1 || property.Longitude != -42.8 {
+		t.Errorf("location = %v,%v, want the extracted one", property.Latitude, property.Longitude)
+	}
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/notifier"
+	"rent-watcher/internal/storage"
+	"sync"
+
+	"github.com/gocolly/colly"
+)
+
+// TrackedURLScraper watches arbitrary listing pages that have no dedicated
+// scraper, relying only on the structured data they embed.
+type TrackedURLScraper struct {
+	BaseScraper
+	Config TrackedURLsConfig
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type TrackedURLsConfig config.TrackedURLsConfig
+
+func NewTrackedURLScraper(config TrackedURLsConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) *TrackedURLScraper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TrackedURLScraper{
+		BaseScraper: BaseScraper{
+			Storage:             storage,
+			Notifier:            notifier,
+			GeolocationProvider: geoProvider,
+			DestinationLat:      destinationLat,
+			DestinationLng:      destinationLng,
+		},
+		Config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (ts *TrackedURLScraper) Scrape(ctx context.Context) (err error) {
+	ts.mu.Lock()
+	ts.ctx, ts.cancel = context.WithCancel(ctx)
+	ts.mu.Unlock()
+	ts.resetStats(ts.ctx, "url")
+	ts.beginRun("url", storage.RunModeFull)
+	defer func() { ts.finishRun(err) }()
+	defer func() {
+		if err == nil {
+			err = ts.checkDrift(ts.Config.Drift)
+		}
+	}()
+
+	c, err := ts.newCollector(ts.Config.UserAgent, ts.Config.HTTPSettings)
+	if err != nil {
+		return err
+	}
+
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		ts.processPage(e)
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		log.Printf("Request URL: %s failed with response: %v\nError: %v\n",
+			r.Request.URL, r, err)
+	})
+
+	for _, listingURL := range ts.Config.URLs {
+		select {
+		case <-ts.ctx.Done():
+			return ts.ctx.Err()
+		default:
+		}
+
+		if err := c.Visit(listingURL); err != nil {
+			if ts.ctx.Err() != nil {
+				return ts.ctx.Err()
+			}
+			log.Printf("Failed to visit tracked URL %s: %v\n", listingURL, err)
+			ts.recordFailure(listingURL, err)
+			continue
+		}
+		ts.updateStats(func(s *RunStats) { s.PagesVisited++ })
+	}
+
+	return nil
+}
+
+func (ts *TrackedURLScraper) processPage(e *colly.HTMLElement) {
+	pageURL := e.Request.URL.String()
+	ts.updateStats(func(s *RunStats) { s.Cards++ })
+	property := ExtractStructuredData(e.DOM)
+	if property.Price == "" && property.Logradouro == "" && property.Latitude == 0 {
+		log.Printf("No structured listing data found on %s\n", pageURL)
+		ts.updateStats(func(s *RunStats) { s.ParseFailures++ })
+		return
+	}
+
+	sum := sha1.Sum([]byte(pageURL))
+	property.ID = "url:" + hex.EncodeToString(sum[:])[:12]
+	property.Source = "url"
+	property.URL = pageURL
+	if !ts.hasRequiredFields(property, ts.Config.Drift.RequiredFields) {
+		return
+	}
+
+	rawData, err := json.Marshal(property)
+	if err != nil {
+		log.Printf("Error encoding extracted data for %s: %v\n", pageURL, err)
+	}
+
+	if err := ts.ProcessProperty(ts.ctx, property, string(rawData)); err != nil {
+		log.Printf("Error processing property: %v\n", err)
+	}
+}
@@ -0,0 +1,218 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/storage"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidProperty is returned by ProcessProperty for records that fail
+// validation. They are counted as rejected and quarantined, not stored.
+var ErrInvalidProperty = errors.New("invalid property")
+
+// Bounds outside of which a scraped value is treated as a parsing mistake
+// rather than a real listing.
+const (
+	maxPrice = 1e9
+	maxArea  = 100000
+	maxRooms = 50
+)
+
+// normalizeProperty trims scraped values and rewrites URLs into their
+// canonical absolute form. Photos that are not valid URLs are dropped, and
+// properties without an operation are rentals.
+func normalizeProperty(property *models.Property) {
+	for _, field := range []*string{
+		&property.ID, &property.Logradouro, &property.Bairro, &property.Cidade, &property.Metragem,
+		&property.Quartos, &property.Banheiros, &property.Suites, &property.Garagens, &property.TipoImovel,
+	} {
+		*field = strings.TrimSpace(*field)
+	}
+	for _, field := range []*string{&property.Price, &property.Condominio, &property.IPTU, &property.YearlyIPTU, &property.TotalPrice} {
+		*field = cleanMoneyString(*field)
+	}
+
+	property.Operation = strings.ToLower(strings.TrimSpace(property.Operation))
+	if property.Operation == "" {
+		property.Operation = models.OperationRent
+	}
+	if property.YearlyIPTU == "" {
+		property.YearlyIPTU = cleanMoneyString(property.Attributes["iptu_anual"])
+	}
+	if property.Financing == "" {
+		property.Financing = property.Attributes["aceita_financiamento"]
+	}
+	switch financing := models.NormalizeAttributeValue(property.Financing); financing {
+	case "true", "false":
+		property.Financing = financing
+	default:
+		property.Financing = ""
+	}
+
+	if u, err := normalizeURL(property.URL); err == nil {
+		property.URL = u
+	}
+	if u, err := normalizeURL(property.FirstPhoto); err == nil {
+		property.FirstPhoto = u
+	} else {
+		property.FirstPhoto = ""
+	}
+
+	photos := property.Photos[:0]
+	for _, photo := range property.Photos {
+		if u, err := normalizeURL(photo); err == nil && u != "" {
+			photos = append(photos, u)
+		}
+	}
+	property.Photos = photos
+}
+
+// normalizeURL returns u as an absolute http(s) URL with a lower-case host and
+// no fragment. Scheme-relative URLs default to https; "" stays "".
+func normalizeURL(u string) (string, error) {
+	u = strings.TrimSpace(u)
+	if u == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(u, "//") {
+		u = "https:" + u
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	return parsed.String(), nil
+}
+
+// validateProperty returns the reasons a normalized property cannot be
+// stored, or nil when it looks sane.
+func validateProperty(property *models.Property) []string {
+	var reasons []string
+	if sourceID(property) == "" {
+		reasons = append(reasons, "missing id")
+	}
+
+	if price, ok := models.ParseNumeric(property.Price); !ok {
+		reasons = append(reasons, fmt.Sprintf("invalid price %q", property.Price))
+	} else if price <= 0 || price > maxPrice {
+		reasons = append(reasons, fmt.Sprintf("price %s out of range", property.Price))
+	}
+	if property.Operation != models.OperationRent && property.Operation != models.OperationSale {
+		reasons = append(reasons, fmt.Sprintf("unknown operation %q", property.Operation))
+	}
+	for _, field := range []namedValue{
+		{"condominio", property.Condominio},
+		{"iptu", property.IPTU},
+		{"iptu_anual", property.YearlyIPTU},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if amount, ok := models.ParseNumeric(field.value); !ok || amount < 0 || amount > maxPrice {
+			reasons = append(reasons, fmt.Sprintf("invalid %s %q", field.name, field.value))
+		}
+	}
+
+	if property.Metragem != "" {
+		if area, ok := models.ParseNumeric(property.Metragem); !ok || area <= 0 || area > maxArea {
+			reasons = append(reasons, fmt.Sprintf("invalid metragem %q", property.Metragem))
+		}
+	}
+	for _, field := range []namedValue{
+		{"quartos", property.Quartos},
+		{"banheiros", property.Banheiros},
+		{"suites", property.Suites},
+		{"garagens", property.Garagens},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if count, ok := parseCount(field.value); !ok || count > maxRooms {
+			reasons = append(reasons, fmt.Sprintf("invalid %s %q", field.name, field.value))
+		}
+	}
+
+	if _, err := normalizeURL(property.URL); err != nil {
+		reasons = append(reasons, fmt.Sprintf("invalid url %q: %v", property.URL, err))
+	}
+	if math.Abs(property.Latitude) > 90 || math.Abs(property.Longitude) > 180 {
+		reasons = append(reasons, fmt.Sprintf("invalid coordinates %f,%f", property.Latitude, property.Longitude))
+	}
+	return reasons
+}
+
+// sourceID returns the ID of property without the "<source>:" prefix scrapers
+// add to it.
+func sourceID(property *models.Property) string {
+	id := property.ID
+	if property.Source != "" {
+		id = strings.TrimPrefix(id, property.Source+":")
+	}
+	return strings.TrimSpace(id)
+}
+
+type namedValue struct {
+	name  string
+	value string
+}
+
+// parseCount reads the leading number of a room count, so values such as
+// "5 ou mais" are accepted.
+func parseCount(value string) (int, bool) {
+	end := 0
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+	count, err := strconv.Atoi(value[:end])
+	if err != nil {
+		return 0, false
+	}
+	return count, true
+}
+
+// rejectProperty counts a record that failed validation and keeps its raw
+// payload in quarantine.
+func (bs *BaseScraper) rejectProperty(property *models.Property, rawData string, reasons []string) {
+	bs.updateStats(func(s *RunStats) { s.Rejected++ })
+	bs.quarantine(property, rawData, reasons)
+}
+
+// quarantine keeps the raw payload of a record that is not stored. Cards that
+// fail before validation are counted by the caller instead of as rejected.
+func (bs *BaseScraper) quarantine(property *models.Property, rawData string, reasons []string) {
+	bs.statsMu.Lock()
+	run, source := bs.run, bs.stats.Source
+	bs.statsMu.Unlock()
+	if property.Source != "" {
+		source = property.Source
+	}
+
+	q := storage.QuarantinedProperty{
+		Source:     source,
+		PropertyID: property.ID,
+		Reasons:    reasons,
+		RawData:    rawData,
+	}
+	if run != nil {
+		q.RunID = run.ID
+	}
+	if err := bs.Storage.QuarantineProperty(q); err != nil {
+		log.Printf("[%s] Error quarantining property %q: %v\n", source, property.ID, err)
+	}
+}
@@ -0,0 +1,25 @@
+package scraper
+
+import (
+	"rent-watcher/internal/models"
+	"testing"
+)
+
+func TestValidatePropertyID(t *testing.T) {
+	for _, tc := range []struct {
+		id, source string
+		valid      bool
+	}{
+		{"123", "", true},
+		{"zap:123", "zap", true},
+		{"", "", false},
+		{"zap:", "zap", false},
+		{"zap: ", "zap", false},
+	} {
+		property := &models.Property{ID: tc.id, Source: tc.source, Price: "1000", Operation: models.OperationRent}
+		reasons := validateProperty(property)
+		if valid := len(reasons) == 0; valid != tc.valid {
+			t.Errorf("validateProperty(id %q, source %q) = %v, want valid %v", tc.id, tc.source, reasons, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"context"
+	"sync"
+)
+
+// detailsPool fetches details pages on a bounded number of workers. Every
+// finished job is handed to a single goroutine, so ProcessProperty and the
+// storage writes it triggers never run concurrently.
+type detailsPool struct {
+	ctx     context.Context
+	jobs    chan *detailsJob
+	results chan *detailsJob
+	workers sync.WaitGroup
+	done    chan struct{}
+}
+
+// newDetailsPool starts workers goroutines running fetch and one goroutine
+// running process on their results.
+func newDetailsPool(ctx context.Context, workers int, fetch, process func(*detailsJob)) *detailsPool {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	p := &detailsPool{
+		ctx:     ctx,
+		jobs:    make(chan *detailsJob, workers),
+		results: make(chan *detailsJob, workers),
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			for job := range p.jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				fetch(job)
+				p.results <- job
+			}
+		}()
+	}
+
+	go func() {
+		defer close(p.done)
+		for job := range p.results {
+			if ctx.Err() != nil {
+				continue
+			}
+			process(job)
+		}
+	}()
+
+	return p
+}
+
+// fetch queues a job for the workers. It returns false if the context was
+// cancelled before the job could be queued.
+func (p *detailsPool) fetch(job *detailsJob) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// process queues a job that needs no fetch straight to the result goroutine.
+func (p *detailsPool) process(job *detailsJob) bool {
+	select {
+	case p.results <- job:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// close waits for queued jobs to finish. Once the context is cancelled the
+// remaining jobs are drained without being fetched or processed.
+func (p *detailsPool) close() {
+	close(p.jobs)
+	p.workers.Wait()
+	close(p.results)
+	<-p.done
+}
@@ -0,0 +1,121 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"rent-watcher/internal/models"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testJob(i int) *detailsJob {
+	return &detailsJob{property: &models.Property{ID: fmt.Sprint(i)}}
+}
+
+func TestDetailsPoolDrainsQueuedJobs(t *testing.T) {
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	processed := make(map[string]int)
+	var concurrentProcess int32
+
+	pool := newDetailsPool(context.Background(), 3, func(job *detailsJob) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		job.fetchedAt = time.Now()
+	}, func(job *detailsJob) {
+		if atomic.AddInt32(&concurrentProcess, 1) > 1 {
+			t.Errorf("process ran concurrently")
+		}
+		mu.Lock()
+		processed[job.property.ID]++
+		mu.Unlock()
+		atomic.AddInt32(&concurrentProcess, -1)
+	})
+
+	for i := 0; i < 20; i++ {
+		if !pool.fetch(testJob(i)) {
+			t.Fatalf("fetch(%d) = false, want the job queued", i)
+		}
+	}
+	if !pool.process(testJob(100)) {
+		t.Fatalf("process = false, want the job queued")
+	}
+	pool.close()
+
+	if len(processed) != 21 {
+		t.Errorf("processed %d jobs after close, want 21", len(processed))
+	}
+	for id, n := range processed {
+		if n != 1 {
+			t.Errorf("job %s processed %d times", id, n)
+		}
+	}
+	if max := atomic.LoadInt32(&maxInFlight); max > 3 {
+		t.Errorf("%d fetches in flight, want at most 3", max)
+	}
+}
+
+func TestDetailsPoolStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 10)
+	var fetched, processed int32
+	pool := newDetailsPool(ctx, 2, func(job *detailsJob) {
+		started <- struct{}{}
+		<-ctx.Done()
+		atomic.AddInt32(&fetched, 1)
+	}, func(job *detailsJob) {
+		atomic.AddInt32(&processed, 1)
+	})
+
+	// Both workers block on their job and the queue fills up, so the next
+	// fetch waits until the context is cancelled.
+	queued := make(chan bool)
+	go func() {
+		for i := 0; ; i++ {
+			if !pool.fetch(testJob(i)) {
+				queued <- false
+				return
+			}
+		}
+	}()
+	<-started
+	<-started
+	cancel()
+
+	select {
+	case ok := <-queued:
+		if ok {
+			t.Fatal("fetch = true after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetch still blocked after cancel")
+	}
+	// A job handed over after cancel may still be queued, but is not processed.
+	pool.process(testJob(100))
+
+	closed := make(chan struct{})
+	go func() {
+		pool.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close did not return after cancel")
+	}
+	if n := atomic.LoadInt32(&fetched); n != 2 {
+		t.Errorf("fetched %d jobs, want only the 2 started before cancel", n)
+	}
+	if n := atomic.LoadInt32(&processed); n != 0 {
+		t.Errorf("processed %d jobs after cancel, want 0", n)
+	}
+}
@@ -0,0 +1,298 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"rent-watcher/internal/config"
+	"rent-watcher/internal/models"
+	"rent-watcher/internal/notifier"
+	"rent-watcher/internal/storage"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	zapDefaultPageSize = 24
+	zapRequestTimeout  = 30 * time.Second
+	zapPhotoSize       = "870x653"
+)
+
+// ZapScraper scrapes ZAP Imóveis and VivaReal through the public listing JSON
+// endpoint both portals use for their search pages.
+type ZapScraper struct {
+	BaseScraper
+	Config     ZapConfig
+	HTTPClient *http.Client
+	mu         sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+type ZapConfig config.ZapConfig
+
+type zapResponse struct {
+	Search struct {
+		Result struct {
+			Listings []zapResult `json:"listings"`
+		} `json:"result"`
+		TotalCount int `json:"totalCount"`
+	} `json:"search"`
+}
+
+type zapResult struct {
+	Listing zapListing `json:"listing"`
+	Medias  []struct {
+		URL  string `json:"url"`
+		Type string `json:"type"`
+	} `json:"medias"`
+	Link struct {
+		Href string `json:"href"`
+	} `json:"link"`
+}
+
+type zapListing struct {
+	ID            string   `json:"id"`
+	UsableAreas   []int    `json:"usableAreas"`
+	Bedrooms      []int    `json:"bedrooms"`
+	Bathrooms     []int    `json:"bathrooms"`
+	Suites        []int    `json:"suites"`
+	ParkingSpaces []int    `json:"parkingSpaces"`
+	UnitTypes     []string `json:"unitTypes"`
+	Amenities     []string `json:"amenities"`
+	PricingInfos  []struct {
+		BusinessType    string `json:"businessType"`
+		Price           string `json:"price"`
+		MonthlyCondoFee string `json:"monthlyCondoFee"`
+		YearlyIptu      string `json:"yearlyIptu"`
+	} `json:"pricingInfos"`
+	Address struct {
+		Street       string `json:"street"`
+		StreetNumber string `json:"streetNumber"`
+		Neighborhood string `json:"neighborhood"`
+		City         string `json:"city"`
+		Point        struct {
+			Lat float64 `json:"lat"`
+			Lon float64 `json:"lon"`
+		} `json:"point"`
+	} `json:"address"`
+}
+
+func NewZapScraper(config ZapConfig, destinationLat, destinationLng float64, storage storage.Storage, notifier notifier.Notifier, geoProvider GeolocationProvider) (*ZapScraper, error) {
+	if config.Name == "" || config.BaseURL == "" || config.Domain == "" {
+		return nil, fmt.Errorf("zap scraper requires name, base_url and domain")
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = zapDefaultPageSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	zs := &ZapScraper{
+		BaseScraper: BaseScraper{
+			Storage:             storage,
+			Notifier:            notifier,
+			GeolocationProvider: geoProvider,
+			DestinationLat:      destinationLat,
+			DestinationLng:      destinationLng,
+			listingPage:         everyPage,
+		},
+		Config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	client, err := zs.newHTTPClient(zapRequestTimeout, config.UserAgent, config.HTTPSettings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	zs.HTTPClient = client
+	return zs, nil
+}
+
+func (zs *ZapScraper) Scrape(ctx context.Context) (err error) {
+	zs.mu.Lock()
+	zs.ctx, zs.cancel = context.WithCancel(ctx)
+	zs.mu.Unlock()
+	zs.resetStats(zs.ctx, zs.Config.Name)
+	zs.beginRun(zs.Config.Name, storage.RunModeFull)
+	defer func() { zs.finishRun(err) }()
+	defer func() {
+		if err == nil {
+			err = zs.checkDrift(zs.Config.Drift)
+		}
+	}()
+
+	limit := maxPages(zs.Config.MaxPages)
+	// firstPage is the first page fetched successfully, which may not be
+	// page 1 when that one fails.
+	firstPage := true
+	for page := 1; page <= limit; page++ {
+		select {
+		case <-zs.ctx.Done():
+			return zs.ctx.Err()
+		default:
+		}
+
+		from := (page - 1) * zs.Config.PageSize
+		log.Printf("[%s] Fetching page %d (from=%d)\n", zs.Config.Name, page, from)
+		response, err := zs.fetchPage(from)
+		if err != nil {
+			if zs.ctx.Err() != nil {
+				return zs.ctx.Err()
+			}
+			log.Printf("[%s] Failed to fetch page %d: %v\n", zs.Config.Name, page, err)
+			zs.recordFailure(fmt.Sprintf("%s?from=%d", zs.Config.BaseURL, from), err)
+			continue
+		}
+
+		zs.updateStats(func(s *RunStats) {
+			s.PagesVisited++
+			s.Cards += len(response.Search.Result.Listings)
+			if firstPage {
+				s.EmptyFirstPage = s.Cards == 0
+			}
+		})
+		firstPage = false
+
+		for _, result := range response.Search.Result.Listings {
+			zs.processResult(result)
+		}
+
+		listings := response.Search.Result.Listings
+		if len(listings) == 0 || from+len(listings) >= response.Search.TotalCount {
+			log.Printf("[%s] Reached the last page (%d)\n", zs.Config.Name, page)
+			zs.updateStats(func(s *RunStats) { s.StopReason = "last page" })
+			return nil
+		}
+	}
+
+	zs.updateStats(func(s *RunStats) { s.StopReason = "max pages reached" })
+	return nil
+}
+
+func (zs *ZapScraper) fetchPage(from int) (*zapResponse, error) {
+	u, err := url.Parse(zs.Config.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base URL: %w", err)
+	}
+
+	q := u.Query()
+	for key, value := range zs.Config.Query {
+		q.Set(key, value)
+	}
+	q.Set("size", strconv.Itoa(zs.Config.PageSize))
+	q.Set("from", strconv.Itoa(from))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(zs.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Domain", zs.Config.Domain)
+	req.Header.Set("User-Agent", zs.Config.UserAgent)
+
+	resp, err := zs.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result zapResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+func (zs *ZapScraper) processResult(result zapResult) {
+	rawData, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("[%s] Error encoding listing %s: %v\n", zs.Config.Name, result.Listing.ID, err)
+	}
+
+	property := zs.mapListing(result)
+	if !zs.hasRequiredFields(property, zs.Config.Drift.RequiredFields) {
+		return
+	}
+
+	if err := zs.ProcessProperty(zs.ctx, property, string(rawData)); err != nil {
+		log.Printf("[%s] Error processing property: %v\n", zs.Config.Name, err)
+	}
+}
+
+func (zs *ZapScraper) mapListing(result zapResult) *models.Property {
+	listing := result.Listing
+	property := &models.Property{
+		ID:         zs.Config.Name + ":" + listing.ID,
+		Source:     zs.Config.Name,
+		Logradouro: strings.TrimSpace(strings.TrimSuffix(listing.Address.Street+", "+listing.Address.StreetNumber, ", ")),
+		Bairro:     listing.Address.Neighborhood,
+		Cidade:     listing.Address.City,
+		Metragem:   firstInt(listing.UsableAreas),
+		Quartos:    firstInt(listing.Bedrooms),
+		Banheiros:  firstInt(listing.Bathrooms),
+		Suites:     firstInt(listing.Suites),
+		Garagens:   firstInt(listing.ParkingSpaces),
+		Latitude:   listing.Address.Point.Lat,
+		Longitude:  listing.Address.Point.Lon,
+	}
+	if len(listing.UnitTypes) > 0 {
+		property.TipoImovel = listing.UnitTypes[0]
+	}
+
+	business := zs.Config.Query["business"]
+	if business == "" {
+		business = "RENTAL"
+	}
+	for _, pricing := range listing.PricingInfos {
+		if !strings.EqualFold(pricing.BusinessType, business) {
+			continue
+		}
+		property.Price = pricing.Price
+		property.Condominio = pricing.MonthlyCondoFee
+		if strings.EqualFold(business, "SALE") {
+			property.Operation = models.OperationSale
+			property.YearlyIPTU = pricing.YearlyIptu
+		} else if yearly, ok := models.ParseNumeric(pricing.YearlyIptu); ok && yearly > 0 {
+			property.IPTU = fmt.Sprintf("%.2f", yearly/12)
+		}
+		break
+	}
+
+	if result.Link.Href != "" {
+		property.URL = strings.TrimSuffix(zs.Config.SiteURL, "/") + result.Link.Href
+	}
+
+	for _, media := range result.Medias {
+		if media.Type != "" && media.Type != "IMAGE" {
+			continue
+		}
+		photo := strings.NewReplacer("{action}", "crop", "{width}x{height}", zapPhotoSize).Replace(media.URL)
+		property.Photos = append(property.Photos, photo)
+	}
+	if len(property.Photos) > 0 {
+		property.FirstPhoto = property.Photos[0]
+	}
+
+	for _, amenity := range listing.Amenities {
+		property.SetAttribute(zs.Config.Name, amenity, "true")
+	}
+
+	return property
+}
+
+func firstInt(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return strconv.Itoa(values[0])
+}
@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"rent-watcher/internal/models"
+	"sync"
+	"testing"
+)
+
+func newTestZapScraper(t *testing.T, baseURL string, query map[string]string, pageSize int) (*ZapScraper, *testNotifier) {
+	t.Helper()
+	notifier := &testNotifier{}
+	zs, err := NewZapScraper(ZapConfig{
+		Name:     "zap",
+		BaseURL:  baseURL,
+		SiteURL:  "https://www.zapimoveis.com.br",
+		Domain:   "www.zapimoveis.com.br",
+		PageSize: pageSize,
+		MaxPages: 5,
+		Query:    query,
+	}, 0, 0, newTestStorage(t), notifier, nil)
+	if err != nil {
+		t.Fatalf("NewZapScraper: %v", err)
+	}
+	return zs, notifier
+}
+
+func TestZapScraperPaging(t *testing.T) {
+	var mu sync.Mutex
+	var pages []string
+	srv := serveFixtures(t, "application/json", func(r *http.Request) string {
+		q := r.URL.Query()
+		mu.Lock()
+		pages = append(pages, q.Get("from")+"/"+q.Get("size"))
+		mu.Unlock()
+		if r.Header.Get("X-Domain") != "www.zapimoveis.com.br" || q.Get("business") != "RENTAL" {
+			return ""
+		}
+		switch q.Get("from") {
+		case "0":
+			return "zap_rental_page1.json"
+		case "2":
+			return "zap_rental_page2.json"
+		}
+		return ""
+	})
+
+	zs, notifier := newTestZapScraper(t, srv.URL, map[string]string{"business": "RENTAL"}, 2)
+	if err := zs.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	if got := len(pages); got != 2 || pages[0] != "0/2" || pages[1] != "2/2" {
+		t.Errorf("requested pages (from/size) = %v, want [0/2 2/2]", pages)
+	}
+	stats := zs.Stats()
+	if stats.PagesVisited != 2 || stats.PropertiesProcessed != 3 || stats.StopReason != "last page" {
+		t.Errorf("stats = %s", stats)
+	}
+
+	notified := notifier.notified()
+	if len(notified) != 3 {
+		t.Fatalf("notified %d properties, want 3", len(notified))
+	}
+
+	first := notified["zap:2712345678"]
+	if first == nil {
+		t.Fatalf("property zap:2712345678 not notified: %v", notified)
+	}
+	for _, tc := range []struct{ field, got, want string }{
+		{"price", first.Price, "1800"},
+		{"condominio", first.Condominio, "350"},
+		{"iptu", first.IPTU, "100.00"},
+		{"operation", first.Operation, models.OperationRent},
+		{"logradouro", first.Logradouro, "Rua Areolino de Abreu, 1200"},
+		{"quartos", first.Quartos, "2"},
+		{"url", first.URL, "https://www.zapimoveis.com.br/imovel/aluguel-apartamento-2-quartos-centro-teresina-pi-68m2-id-2712345678/"},
+		{"first_foto", first.FirstPhoto, "https://resizedimgs.zapimoveis.com.br/crop/870x653/named.images.sp/abc/foto1.jpg"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+	if len(first.Photos) != 1 {
+		t.Errorf("photos = %v, want only the image", first.Photos)
+	}
+
+	if got := notified["zap:2712345679"].IPTU; got != "" {
+		t.Errorf("iptu without yearly iptu = %q, want empty", got)
+	}
+	// Only the pricing of the searched business type is used.
+	house := notified["zap:2712345680"]
+	if house.Price != "2500" || house.IPTU != "50.00" {
+		t.Errorf("house price/iptu = %q/%q, want 2500/50.00", house.Price, house.IPTU)
+	}
+}
+
+func TestZapScraperSale(t *testing.T) {
+	srv := serveFixtures(t, "application/json", func(r *http.Request) string {
+		if r.URL.Query().Get("business") != "SALE" {
+			return ""
+		}
+		return "zap_sale.json"
+	})
+
+	zs, notifier := newTestZapScraper(t, srv.URL, map[string]string{"business": "SALE"}, 0)
+	if err := zs.Scrape(context.Background()); err != nil {
+		t.Fatalf("Scrape: %v", err)
+	}
+
+	property := notifier.notified()["zap:2798765432"]
+	if property == nil {
+		t.Fatalf("sale listing not notified")
+	}
+	if !property.IsSale() {
+		t.Errorf("operation = %q, want %q", property.Operation, models.OperationSale)
+	}
+	if property.Price != "540000" || property.TotalPrice != "540000" {
+		t.Errorf("price/total = %q/%q, want the asking price", property.Price, property.TotalPrice)
+	}
+	if property.YearlyIPTU != "1850" || property.IPTU != "" {
+		t.Errorf("yearly/monthly iptu = %q/%q, want 1850 and empty", property.YearlyIPTU, property.IPTU)
+	}
+	if property.PricePerM2 != "6000.00" {
+		t.Errorf("price per m² = %q, want 6000.00", property.PricePerM2)
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Checkpoint is the progress of an unfinished run of a source: the next
+// listing page to visit and the details still pending, encoded by the scraper.
+// Page 0 means every listing page was visited.
+type Checkpoint struct {
+	Source    string
+	RunID     int64
+	Mode      string
+	Search    string
+	Page      int
+	Pending   string
+	UpdatedAt time.Time
+}
+
+// GetCheckpoint returns the checkpoint of a source, or nil when there is none.
+func (s *SQLStorage) GetCheckpoint(source string) (*Checkpoint, error) {
+	cp := Checkpoint{Source: source}
+	var pending sql.NullString
+	err := s.db.QueryRow(`
+		SELECT run_id, mode, search, page, pending, updated_at
+		FROM scrape_checkpoints WHERE source = ?`, source).
+		Scan(&cp.RunID, &cp.Mode, &cp.Search, &cp.Page, &pending, &cp.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	cp.Pending = pending.String
+	return &cp, nil
+}
+
+func (s *SQLStorage) SaveCheckpoint(cp *Checkpoint) error {
+	cp.UpdatedAt = time.Now().UTC()
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO scrape_checkpoints (source, run_id, mode, search, page, pending, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		cp.Source, cp.RunID, cp.Mode, cp.Search, cp.Page, cp.Pending, cp.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStorage) DeleteCheckpoint(source string) error {
+	if _, err := s.db.Exec("DELETE FROM scrape_checkpoints WHERE source = ?", source); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FetchState records what was last scraped for a property: a hash of the
+// listing payload and when its details page was last fetched.
+type FetchState struct {
+	PayloadHash      string
+	DetailsFetchedAt time.Time
+}
+
+// GetFetchState returns the fetch state of a property, or nil when the
+// property is not stored.
+func (s *SQLStorage) GetFetchState(propertyID string) (*FetchState, error) {
+	var hash sql.NullString
+	var fetchedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT payload_hash, details_fetched_at
+		FROM properties WHERE id = ?`, propertyID).Scan(&hash, &fetchedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get fetch state: %w", err)
+	}
+	return &FetchState{PayloadHash: hash.String, DetailsFetchedAt: fetchedAt.Time}, nil
+}
+
+// SaveFetchState updates the fetch state of an already stored property.
+func (s *SQLStorage) SaveFetchState(propertyID string, state FetchState) error {
+	var fetchedAt interface{}
+	if !state.DetailsFetchedAt.IsZero() {
+		fetchedAt = state.DetailsFetchedAt.UTC()
+	}
+	_, err := s.db.Exec(`
+		UPDATE properties SET payload_hash = ?, details_fetched_at = ?
+		WHERE id = ?`, state.PayloadHash, fetchedAt, propertyID)
+	if err != nil {
+		return fmt.Errorf("failed to save fetch state: %w", err)
+	}
+	return nil
+}
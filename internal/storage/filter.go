@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rent-watcher/internal/models"
+)
+
+// AttributeFilter compares a property attribute against a value.
+// Ordering operators compare numeric_value, equality compares numbers
+// numerically and anything else as normalized text.
+type AttributeFilter struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+var (
+	filterClauseRegex = regexp.MustCompile(`^attr\.([a-z0-9_]+)\s*(==|!=|>=|<=|=|>|<)\s*(.+)$`)
+	filterAndRegex    = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// ParseAttributeFilter parses expressions such as
+// "attr.aceita_pets = true and attr.suites >= 1".
+func ParseAttributeFilter(expr string) ([]AttributeFilter, error) {
+	var filters []AttributeFilter
+	for _, clause := range filterAndRegex.Split(strings.TrimSpace(expr), -1) {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		m := filterClauseRegex.FindStringSubmatch(clause)
+		if m == nil {
+			return nil, fmt.Errorf("invalid attribute filter %q", clause)
+		}
+
+		op := m[2]
+		if op == "==" {
+			op = "="
+		}
+		value := strings.Trim(strings.TrimSpace(m[3]), `"'`)
+		filters = append(filters, AttributeFilter{Key: m[1], Operator: op, Value: value})
+	}
+	return filters, nil
+}
+
+func (f AttributeFilter) sql() (string, []interface{}, error) {
+	value := models.NormalizeAttributeValue(f.Value)
+	numeric, isNumeric := models.ParseNumeric(value)
+
+	const exists = "EXISTS (SELECT 1 FROM property_attributes a WHERE a.property_id = p.id AND a.key = ? AND %s)"
+	switch f.Operator {
+	case "=", "!=":
+		cond, arg := "a.value = ?", interface{}(value)
+		if isNumeric {
+			cond, arg = "a.numeric_value = ?", numeric
+		}
+		clause := fmt.Sprintf(exists, cond)
+		if f.Operator == "!=" {
+			clause = "NOT " + clause
+		}
+		return clause, []interface{}{f.Key, arg}, nil
+	case ">", ">=", "<", "<=":
+		if !isNumeric {
+			return "", nil, fmt.Errorf("operator %s requires a numeric value for attr.%s", f.Operator, f.Key)
+		}
+		return fmt.Sprintf(exists, "a.numeric_value "+f.Operator+" ?"), []interface{}{f.Key, numeric}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operator %q", f.Operator)
+	}
+}
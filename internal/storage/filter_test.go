@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"net/url"
+	"rent-watcher/internal/database"
+	"rent-watcher/internal/models"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newTestStorage(t *testing.T) Storage {
+	t.Helper()
+	db, err := database.Init("file:" + url.PathEscape(t.Name()) + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewSQLStorage(db)
+}
+
+func TestParseAttributeFilter(t *testing.T) {
+	filters, err := ParseAttributeFilter(`attr.aceita_pets == "sim" AND attr.suites >= 1`)
+	if err != nil {
+		t.Fatalf("ParseAttributeFilter: %v", err)
+	}
+	want := []AttributeFilter{
+		{Key: "aceita_pets", Operator: "=", Value: "sim"},
+		{Key: "suites", Operator: ">=", Value: "1"},
+	}
+	if len(filters) != len(want) {
+		t.Fatalf("filters = %v, want %v", filters, want)
+	}
+	for i := range want {
+		if filters[i] != want[i] {
+			t.Errorf("filter %d = %v, want %v", i, filters[i], want[i])
+		}
+	}
+
+	if filters, err := ParseAttributeFilter("  "); err != nil || len(filters) != 0 {
+		t.Errorf("empty expression = %v, %v; want no filters", filters, err)
+	}
+	for _, expr := range []string{"suites >= 1", "attr.suites ~ 1", "attr.Suites = 1"} {
+		if _, err := ParseAttributeFilter(expr); err == nil {
+			t.Errorf("ParseAttributeFilter(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestFindPropertiesByAttribute(t *testing.T) {
+	s := newTestStorage(t)
+	for id, attributes := range map[string]map[string]string{
+		"a": {"aceita_pets": "Sim", "suites": "2"},
+		"b": {"aceita_pets": "Não", "suites": "1"},
+		"c": {"suites": "3", "mobiliado": "true"},
+	} {
+		property := &models.Property{ID: id, Source: "test", Price: "1000", Attributes: make(map[string]string)}
+		for key, value := range attributes {
+			property.Attributes[key] = models.NormalizeAttributeValue(value)
+		}
+		if err := s.SaveOrUpdateProperty(property, ""); err != nil {
+			t.Fatalf("SaveOrUpdateProperty(%s): %v", id, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{"", "a,b,c"},
+		{"attr.aceita_pets = true", "a"},
+		{"attr.aceita_pets != true", "b,c"},
+		{"attr.suites >= 2", "a,c"},
+		{"attr.suites = 1.0", "b"},
+		{"attr.suites > 1 and attr.aceita_pets = sim", "a"},
+		{"attr.varanda = true", ""},
+	} {
+		filters, err := ParseAttributeFilter(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseAttributeFilter(%q): %v", tc.expr, err)
+		}
+		properties, err := s.FindProperties(filters...)
+		if err != nil {
+			t.Fatalf("FindProperties(%q): %v", tc.expr, err)
+		}
+		var ids []string
+		for _, p := range properties {
+			ids = append(ids, p.ID)
+		}
+		sort.Strings(ids)
+		if got := strings.Join(ids, ","); got != tc.want {
+			t.Errorf("FindProperties(%q) = %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+
+	filters, _ := ParseAttributeFilter("attr.suites > muitas")
+	if _, err := s.FindProperties(filters...); err == nil {
+		t.Error("ordering a text value succeeded, want an error")
+	}
+}
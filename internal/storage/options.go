@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SourceOption is one value of a search filter offered by a source, such as a
+// neighbourhood of the Arantes search form.
+type SourceOption struct {
+	Field string
+	Value string
+	Label string
+}
+
+// SourceOptions returns the cached filter options of a source and when they
+// were fetched. A zero time means nothing is cached.
+func (s *SQLStorage) SourceOptions(source string) ([]SourceOption, time.Time, error) {
+	rows, err := s.db.Query(`
+		SELECT field, value, label, fetched_at
+		FROM source_options WHERE source = ?
+		ORDER BY field, label`, source)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to query source options: %w", err)
+	}
+	defer rows.Close()
+
+	var options []SourceOption
+	var fetchedAt time.Time
+	for rows.Next() {
+		var option SourceOption
+		if err := rows.Scan(&option.Field, &option.Value, &option.Label, &fetchedAt); err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to scan source option: %w", err)
+		}
+		options = append(options, option)
+	}
+	return options, fetchedAt, rows.Err()
+}
+
+// ReplaceSourceOptions overwrites the cached filter options of a source.
+func (s *SQLStorage) ReplaceSourceOptions(source string, options []SourceOption) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	if _, err := tx.Exec("DELETE FROM source_options WHERE source = ?", source); err != nil {
+		return fmt.Errorf("failed to clear source options: %w", err)
+	}
+	now := time.Now().UTC()
+	for _, option := range options {
+		_, err := tx.Exec(`
+			INSERT OR REPLACE INTO source_options (source, field, value, label, fetched_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			source, option.Field, option.Value, option.Label, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert source option: %w", err)
+		}
+	}
+	return tx.Commit()
+}
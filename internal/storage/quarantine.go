@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuarantinedProperty is a scraped record rejected by validation, kept with
+// its raw payload so the scraper can be fixed against real data.
+type QuarantinedProperty struct {
+	Source     string
+	PropertyID string
+	RunID      int64
+	Reasons    []string
+	RawData    string
+	CreatedAt  time.Time
+}
+
+func (s *SQLStorage) QuarantineProperty(q QuarantinedProperty) error {
+	var runID sql.NullInt64
+	if q.RunID != 0 {
+		runID = sql.NullInt64{Int64: q.RunID, Valid: true}
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO quarantine (source, property_id, run_id, reasons, raw_data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		q.Source, q.PropertyID, runID, strings.Join(q.Reasons, "; "), q.RawData, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to quarantine property: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	RunModeFull        = "full"
+	RunModeIncremental = "incremental"
+
+	RunStatusRunning   = "running"
+	RunStatusCompleted = "completed"
+	RunStatusPartial   = "partial"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+)
+
+// ScrapeRun is the persisted record of one Scrape run of a source.
+type ScrapeRun struct {
+	ID                  int64
+	Source              string
+	Mode                string
+	Status              string
+	PagesVisited        int
+	PropertiesProcessed int
+	StartedAt           time.Time
+	FinishedAt          time.Time
+	Failures            []RunFailure
+}
+
+// RunFailure is a page a run could not fetch.
+type RunFailure struct {
+	URL   string
+	Error string
+}
+
+func (s *SQLStorage) StartRun(source, mode string) (*ScrapeRun, error) {
+	run := &ScrapeRun{
+		Source:    source,
+		Mode:      mode,
+		Status:    RunStatusRunning,
+		StartedAt: time.Now().UTC(),
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO scrape_runs (source, mode, status, started_at)
+		VALUES (?, ?, ?, ?)`,
+		run.Source, run.Mode, run.Status, run.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start run: %w", err)
+	}
+
+	run.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get run id: %w", err)
+	}
+	return run, nil
+}
+
+func (s *SQLStorage) FinishRun(run *ScrapeRun) error {
+	run.FinishedAt = time.Now().UTC()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+	}()
+
+	_, err = tx.Exec(`
+		UPDATE scrape_runs
+		SET status = ?, pages_visited = ?, properties_processed = ?, finished_at = ?
+		WHERE id = ?`,
+		run.Status, run.PagesVisited, run.PropertiesProcessed, run.FinishedAt, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to finish run: %w", err)
+	}
+
+	for _, failure := range run.Failures {
+		_, err := tx.Exec(`
+			INSERT INTO scrape_run_failures (run_id, url, error)
+			VALUES (?, ?, ?)`, run.ID, failure.URL, failure.Error)
+		if err != nil {
+			return fmt.Errorf("failed to record run failure: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit run: %w", err)
+	}
+	return nil
+}
+
+// LastRun returns the most recent run of a source with the given mode and
+// status, or nil when there is none.
+func (s *SQLStorage) LastRun(source, mode, status string) (*ScrapeRun, error) {
+	run, err := scanRun(s.db.QueryRow(`
+		SELECT `+runColumns+`
+		FROM scrape_runs
+		WHERE source = ? AND mode = ? AND status = ?
+		ORDER BY started_at DESC LIMIT 1`,
+		source, mode, status))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get last run: %w", err)
+	}
+	return run, nil
+}
+
+// RecentRuns returns up to limit of the latest completed or partial runs of a
+// source in the given mode, newest first.
+func (s *SQLStorage) RecentRuns(source, mode string, limit int) ([]*ScrapeRun, error) {
+	rows, err := s.db.Query(`
+		SELECT `+runColumns+`
+		FROM scrape_runs
+		WHERE source = ? AND mode = ? AND status IN (?, ?)
+		ORDER BY started_at DESC LIMIT ?`,
+		source, mode, RunStatusCompleted, RunStatusPartial, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*ScrapeRun
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+const runColumns = `id, source, mode, status, pages_visited, properties_processed, started_at, finished_at`
+
+func scanRun(row rowScanner) (*ScrapeRun, error) {
+	var run ScrapeRun
+	var finishedAt sql.NullTime
+	err := row.Scan(&run.ID, &run.Source, &run.Mode, &run.Status, &run.PagesVisited, &run.PropertiesProcessed,
+		&run.StartedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	run.FinishedAt = finishedAt.Time
+	return &run, nil
+}
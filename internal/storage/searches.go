@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// SaveSearchMatches records the searches that found a property in the last
+// run. Properties that are not stored are ignored.
+func (s *SQLStorage) SaveSearchMatches(propertyID string, searches []string) error {
+	now := time.Now().UTC()
+	for _, search := range searches {
+		_, err := s.db.Exec(`
+			INSERT INTO property_searches (property_id, search, last_seen_at)
+			SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM properties WHERE id = ?)
+			ON CONFLICT (property_id, search) DO UPDATE SET last_seen_at = excluded.last_seen_at`,
+			propertyID, search, now, propertyID)
+		if err != nil {
+			return fmt.Errorf("failed to save search %q: %w", search, err)
+		}
+	}
+	return nil
+}
@@ -2,16 +2,35 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"rent-watcher/internal/models"
+	"strings"
+	"time"
 )
 
 type Storage interface {
 	GetProperty(propertyID string) (*models.Property, error)
 	PropertyExists(propertyID string) (bool, error)
 	SaveOrUpdateProperty(property *models.Property, rawData string) error
+	FindProperties(filters ...AttributeFilter) ([]*models.Property, error)
+	PendingEnrichment(source string) ([]*models.Property, error)
+	SaveDistance(propertyID string, distanceMeters int) error
+	GetFetchState(propertyID string) (*FetchState, error)
+	SaveFetchState(propertyID string, state FetchState) error
+	StartRun(source, mode string) (*ScrapeRun, error)
+	FinishRun(run *ScrapeRun) error
+	LastRun(source, mode, status string) (*ScrapeRun, error)
+	RecentRuns(source, mode string, limit int) ([]*ScrapeRun, error)
+	QuarantineProperty(q QuarantinedProperty) error
+	SaveSearchMatches(propertyID string, searches []string) error
+	SourceOptions(source string) ([]SourceOption, time.Time, error)
+	ReplaceSourceOptions(source string, options []SourceOption) error
+	GetCheckpoint(source string) (*Checkpoint, error)
+	SaveCheckpoint(cp *Checkpoint) error
+	DeleteCheckpoint(source string) error
 }
 
 type SQLStorage struct {
@@ -22,21 +41,128 @@ func NewSQLStorage(db *sql.DB) Storage {
 	return &SQLStorage{db: db}
 }
 
-func (s *SQLStorage) GetProperty(propertyID string) (*models.Property, error) {
+const propertyColumns = `id, first_photo, price, logradouro, bairro, cidade, metragem, quartos, banheiros, suites, garagens,
+	tipo_imovel, distance_meters, condominio, total_price, source, url, latitude, longitude, photos, iptu,
+	operation, yearly_iptu, financing, price_per_m2, enrichment_pending`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProperty(row rowScanner) (*models.Property, error) {
 	var property models.Property
-	err := s.db.QueryRow(`
-		SELECT id, first_photo, price, logradouro, bairro, cidade, metragem, quartos, banheiros, suites, garagens, tipo_imovel, distance_meters
-		FROM properties WHERE id = ?`, propertyID).Scan(
+	var condominio, totalPrice, source, url, photos, iptu sql.NullString
+	var operation, yearlyIPTU, financing, pricePerM2 sql.NullString
+	var latitude, longitude sql.NullFloat64
+	err := row.Scan(
 		&property.ID, &property.FirstPhoto, &property.Price, &property.Logradouro, &property.Bairro, &property.Cidade,
 		&property.Metragem, &property.Quartos, &property.Banheiros, &property.Suites, &property.Garagens, &property.TipoImovel,
-		&property.DistanceMeters)
+		&property.DistanceMeters, &condominio, &totalPrice, &source, &url, &latitude, &longitude, &photos, &iptu,
+		&operation, &yearlyIPTU, &financing, &pricePerM2, &property.EnrichPending)
+	if err != nil {
+		return nil, err
+	}
+	if photos.String != "" {
+		if err := json.Unmarshal([]byte(photos.String), &property.Photos); err != nil {
+			return nil, fmt.Errorf("failed to decode photos: %w", err)
+		}
+	}
+	property.Condominio = condominio.String
+	property.TotalPrice = totalPrice.String
+	property.Source = source.String
+	property.URL = url.String
+	property.IPTU = iptu.String
+	property.Operation = operation.String
+	property.YearlyIPTU = yearlyIPTU.String
+	property.Financing = financing.String
+	property.PricePerM2 = pricePerM2.String
+	property.Latitude = latitude.Float64
+	property.Longitude = longitude.Float64
+	return &property, nil
+}
+
+func (s *SQLStorage) GetProperty(propertyID string) (*models.Property, error) {
+	property, err := scanProperty(s.db.QueryRow(`
+		SELECT `+propertyColumns+`
+		FROM properties WHERE id = ?`, propertyID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("property not found: %w", err)
 		}
 		return nil, fmt.Errorf("failed to get property: %w", err)
 	}
-	return &property, nil
+
+	property.Attributes, err = s.getAttributes(property.ID)
+	if err != nil {
+		return nil, err
+	}
+	return property, nil
+}
+
+// FindProperties returns every stored property matching all the given attribute filters.
+func (s *SQLStorage) FindProperties(filters ...AttributeFilter) ([]*models.Property, error) {
+	query := "SELECT " + propertyColumns + " FROM properties p"
+	var conditions []string
+	var args []interface{}
+	for _, f := range filters {
+		clause, clauseArgs, err := f.sql()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, clause)
+		args = append(args, clauseArgs...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY p.created_at DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query properties: %w", err)
+	}
+	defer rows.Close()
+
+	var properties []*models.Property
+	for rows.Next() {
+		property, err := scanProperty(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan property: %w", err)
+		}
+		properties = append(properties, property)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate properties: %w", err)
+	}
+
+	for _, property := range properties {
+		if property.Attributes, err = s.getAttributes(property.ID); err != nil {
+			return nil, err
+		}
+	}
+	return properties, nil
+}
+
+func (s *SQLStorage) getAttributes(propertyID string) (map[string]string, error) {
+	rows, err := s.db.Query("SELECT key, value FROM property_attributes WHERE property_id = ?", propertyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attributes: %w", err)
+	}
+	defer rows.Close()
+
+	var attributes map[string]string
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute: %w", err)
+		}
+		if attributes == nil {
+			attributes = make(map[string]string)
+		}
+		attributes[key] = value.String
+	}
+	return attributes, rows.Err()
 }
 
 func (s *SQLStorage) SaveOrUpdateProperty(property *models.Property, rawData string) error {
@@ -72,6 +198,12 @@ func (s *SQLStorage) SaveOrUpdateProperty(property *models.Property, rawData str
 			return err
 		}
 
+		if property.Attributes != nil {
+			if err := s.replaceAttributes(tx, property.ID, property.Attributes); err != nil {
+				return err
+			}
+		}
+
 		return tx.Commit()
 	}()
 
@@ -94,14 +226,33 @@ func (s *SQLStorage) propertyExistsInTx(tx *sql.Tx, propertyID string) (bool, er
 	return true, nil
 }
 
+func encodePhotos(photos []string) (string, error) {
+	if len(photos) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(photos)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode photos: %w", err)
+	}
+	return string(encoded), nil
+}
+
 func (s *SQLStorage) insertPropertyData(tx *sql.Tx, property *models.Property) error {
-	_, err := tx.Exec(`
+	photos, err := encodePhotos(property.Photos)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
 		INSERT INTO properties 
-		(id, first_photo, price, logradouro, bairro, cidade, metragem, quartos, banheiros, suites, garagens, tipo_imovel, distance_meters, condominio, total_price)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		(id, first_photo, price, logradouro, bairro, cidade, metragem, quartos, banheiros, suites, garagens, tipo_imovel, distance_meters, condominio, total_price, source, url,
+		 latitude, longitude, photos, iptu, operation, yearly_iptu, financing, price_per_m2, enrichment_pending)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		property.ID, property.FirstPhoto, property.Price, property.Logradouro, property.Bairro, property.Cidade,
 		property.Metragem, property.Quartos, property.Banheiros, property.Suites, property.Garagens, property.TipoImovel,
-		property.DistanceMeters, property.Condominio, property.TotalPrice)
+		property.DistanceMeters, property.Condominio, property.TotalPrice, property.Source, property.URL,
+		property.Latitude, property.Longitude, photos, property.IPTU,
+		property.Operation, property.YearlyIPTU, property.Financing, property.PricePerM2, property.EnrichPending)
 	if err != nil {
 		return fmt.Errorf("failed to insert property: %w", err)
 	}
@@ -109,14 +260,25 @@ func (s *SQLStorage) insertPropertyData(tx *sql.Tx, property *models.Property) e
 }
 
 func (s *SQLStorage) updatePropertyData(tx *sql.Tx, property *models.Property) error {
-	_, err := tx.Exec(`
+	photos, err := encodePhotos(property.Photos)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
 		UPDATE properties 
 		SET first_photo = ?, price = ?, logradouro = ?, bairro = ?, cidade = ?, metragem = ?, 
-			quartos = ?, banheiros = ?, suites = ?, garagens = ?, tipo_imovel = ?, condominio = ?, total_price = ?
+			quartos = ?, banheiros = ?, suites = ?, garagens = ?, tipo_imovel = ?, condominio = ?, total_price = ?,
+			source = ?, url = ?, latitude = ?, longitude = ?, photos = ?, iptu = ?,
+			operation = ?, yearly_iptu = ?, financing = ?, price_per_m2 = ?,
+			distance_meters = ?, enrichment_pending = ?
 		WHERE id = ?`,
 		property.FirstPhoto, property.Price, property.Logradouro, property.Bairro, property.Cidade,
 		property.Metragem, property.Quartos, property.Banheiros, property.Suites, property.Garagens, property.TipoImovel,
-		property.Condominio, property.TotalPrice, property.ID)
+		property.Condominio, property.TotalPrice, property.Source, property.URL,
+		property.Latitude, property.Longitude, photos, property.IPTU,
+		property.Operation, property.YearlyIPTU, property.Financing, property.PricePerM2,
+		property.DistanceMeters, property.EnrichPending, property.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update property: %w", err)
 	}
@@ -134,6 +296,60 @@ func (s *SQLStorage) upsertRawData(tx *sql.Tx, id, rawData string) error {
 	return nil
 }
 
+// replaceAttributes overwrites the stored attributes of a property. Callers that
+// did not scrape attributes leave Property.Attributes nil so existing ones are kept.
+func (s *SQLStorage) replaceAttributes(tx *sql.Tx, id string, attributes map[string]string) error {
+	if _, err := tx.Exec("DELETE FROM property_attributes WHERE property_id = ?", id); err != nil {
+		return fmt.Errorf("failed to clear attributes: %w", err)
+	}
+
+	for key, value := range attributes {
+		var numericValue sql.NullFloat64
+		if f, ok := models.ParseNumeric(value); ok {
+			numericValue = sql.NullFloat64{Float64: f, Valid: true}
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO property_attributes (property_id, key, value, numeric_value)
+			VALUES (?, ?, ?, ?)`,
+			id, key, value, numericValue)
+		if err != nil {
+			return fmt.Errorf("failed to insert attribute %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// PendingEnrichment returns the properties of source whose distance is still
+// to be calculated.
+func (s *SQLStorage) PendingEnrichment(source string) ([]*models.Property, error) {
+	rows, err := s.db.Query("SELECT "+propertyColumns+" FROM properties p WHERE enrichment_pending = 1 AND source = ?", source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending enrichment: %w", err)
+	}
+	defer rows.Close()
+
+	var properties []*models.Property
+	for rows.Next() {
+		property, err := scanProperty(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan property: %w", err)
+		}
+		properties = append(properties, property)
+	}
+	return properties, rows.Err()
+}
+
+// SaveDistance stores the distance of a property and clears its pending
+// enrichment.
+func (s *SQLStorage) SaveDistance(propertyID string, distanceMeters int) error {
+	_, err := s.db.Exec("UPDATE properties SET distance_meters = ?, enrichment_pending = 0 WHERE id = ?", distanceMeters, propertyID)
+	if err != nil {
+		return fmt.Errorf("failed to save distance: %w", err)
+	}
+	return nil
+}
+
 func (s *SQLStorage) PropertyExists(propertyID string) (bool, error) {
 	var id string
 	err := s.db.QueryRow("SELECT id FROM properties WHERE id = ?", propertyID).Scan(&id)
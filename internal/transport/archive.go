@@ -0,0 +1,168 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"rent-watcher/internal/config"
+	"sync"
+	"time"
+)
+
+const (
+	ArchiveRecord = "record"
+	ArchiveReplay = "replay"
+)
+
+// ErrNotArchived is returned in replay mode for requests missing from the
+// archive.
+var ErrNotArchived = errors.New("request not in archive")
+
+// Archive records every request and response that goes through it into a
+// directory, or replays a recorded directory without touching the network.
+// Each exchange is stored as <key>-<n>.json, where key identifies the method,
+// URL and request body and n counts repeated requests, so a replayed run sees
+// the same responses in the same order as the recorded one. Once a key's
+// recorded responses are used up, the last one is served again.
+type Archive struct {
+	next   http.RoundTripper
+	dir    string
+	replay bool
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+type archiveEntry struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+	RecordedAt  time.Time   `json:"recorded_at"`
+}
+
+// NewArchive wraps next in the mode of archive. In replay mode next is never
+// used.
+func NewArchive(next http.RoundTripper, archive config.ArchiveConfig) (*Archive, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if archive.Dir == "" {
+		return nil, fmt.Errorf("archive requires a dir")
+	}
+
+	switch archive.Mode {
+	case ArchiveRecord:
+		if err := os.MkdirAll(archive.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create archive dir: %w", err)
+		}
+	case ArchiveReplay:
+		if _, err := os.Stat(archive.Dir); err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown archive mode %q", archive.Mode)
+	}
+
+	return &Archive{
+		next:   next,
+		dir:    archive.Dir,
+		replay: archive.Mode == ArchiveReplay,
+		counts: make(map[string]int),
+	}, nil
+}
+
+func (a *Archive) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	key := archiveKey(req.Method, req.URL.String(), requestBody)
+	a.mu.Lock()
+	n := a.counts[key]
+	a.counts[key]++
+	a.mu.Unlock()
+
+	if a.replay {
+		return a.load(req, key, n)
+	}
+
+	resp, err := a.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := archiveEntry{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(requestBody),
+		Status:      resp.StatusCode,
+		Header:      resp.Header.Clone(),
+		Body:        body,
+		RecordedAt:  time.Now(),
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archive entry: %w", err)
+	}
+	if err := writeFileAtomic(a.path(key, n), data); err != nil {
+		return nil, fmt.Errorf("failed to write archive entry: %w", err)
+	}
+	return resp, nil
+}
+
+// load returns the n-th recorded response for key, or the last one recorded.
+func (a *Archive) load(req *http.Request, key string, n int) (*http.Response, error) {
+	for ; n >= 0; n-- {
+		data, err := os.ReadFile(a.path(key, n))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive entry: %w", err)
+		}
+
+		var entry archiveEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode archive entry: %w", err)
+		}
+		cached := cacheEntry{URL: entry.URL, Status: entry.Status, Header: entry.Header, Body: entry.Body}
+		return cached.response(req), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNotArchived, req.Method, req.URL)
+}
+
+func (a *Archive) path(key string, n int) string {
+	return filepath.Join(a.dir, fmt.Sprintf("%s-%d.json", key, n))
+}
+
+func archiveKey(method, rawURL string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, rawURL)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rent-watcher/internal/config"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestArchiveRecordAndReplay(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Hit", fmt.Sprint(n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %s #%d", r.Method, r.URL.Path, body, n)
+	}))
+	dir := t.TempDir()
+
+	recorder, err := NewArchive(nil, config.ArchiveConfig{Mode: ArchiveRecord, Dir: dir})
+	if err != nil {
+		t.Fatalf("NewArchive(record): %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+	recorded := []string{
+		get(t, client, srv.URL+"/listagem?page=1"),
+		get(t, client, srv.URL+"/listagem?page=1"),
+		post(t, client, srv.URL+"/search", `{"offset":0}`),
+	}
+	srv.Close()
+
+	replayer, err := NewArchive(nil, config.ArchiveConfig{Mode: ArchiveReplay, Dir: dir})
+	if err != nil {
+		t.Fatalf("NewArchive(replay): %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+
+	// Repeated requests replay in recorded order, then repeat the last one.
+	for i, want := range []string{recorded[0], recorded[1], recorded[1]} {
+		if got := get(t, client, srv.URL+"/listagem?page=1"); got != want {
+			t.Errorf("replayed GET #%d = %q, want %q", i, got, want)
+		}
+	}
+	if got := post(t, client, srv.URL+"/search", `{"offset":0}`); got != recorded[2] {
+		t.Errorf("replayed POST = %q, want %q", got, recorded[2])
+	}
+
+	for _, req := range []*http.Request{
+		mustRequest(t, http.MethodGet, srv.URL+"/listagem?page=2", ""),
+		mustRequest(t, http.MethodPost, srv.URL+"/search", `{"offset":36}`),
+	} {
+		resp, err := replayer.RoundTrip(req)
+		if !errors.Is(err, ErrNotArchived) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			t.Errorf("%s %s: err = %v, want ErrNotArchived", req.Method, req.URL, err)
+		}
+	}
+	if hits := atomic.LoadInt32(&hits); hits != 3 {
+		t.Errorf("server saw %d requests, want only the 3 recorded", hits)
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	return do(t, client, mustRequest(t, http.MethodGet, url, ""))
+}
+
+func post(t *testing.T, client *http.Client, url, body string) string {
+	t.Helper()
+	return do(t, client, mustRequest(t, http.MethodPost, url, body))
+}
+
+func mustRequest(t *testing.T, method, url, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// do returns the status, X-Hit header and body of the response to req.
+func do(t *testing.T, client *http.Client, req *http.Request) string {
+	t.Helper()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", req.Method, req.URL, err)
+	}
+	return fmt.Sprintf("%d hit=%s %s", resp.StatusCode, resp.Header.Get("X-Hit"), body)
+}
@@ -0,0 +1,201 @@
+package transport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"rent-watcher/internal/config"
+	"strings"
+	"time"
+)
+
+// Cache lookup results reported through Cache.OnResult.
+const (
+	CacheHit         = "hit"
+	CacheRevalidated = "revalidated"
+	CacheMiss        = "miss"
+)
+
+// Cache is an on-disk HTTP cache for GET requests, keyed by URL. Entries
+// younger than MaxAge are served without a request; older ones are
+// revalidated with If-None-Match / If-Modified-Since and served again on a
+// 304. Requests Revalidate reports true for are revalidated whatever their
+// age. With DevReplay every cached entry is served regardless of its age.
+type Cache struct {
+	next       http.RoundTripper
+	config     config.CacheConfig
+	OnResult   func(req *http.Request, result string)
+	Revalidate func(req *http.Request) bool
+}
+
+type cacheEntry struct {
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// NewCache wraps next with an on-disk cache in cache.Dir.
+func NewCache(next http.RoundTripper, cache config.CacheConfig) *Cache {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Cache{next: next, config: cache}
+}
+
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return c.next.RoundTrip(req)
+	}
+
+	path := c.path(req.URL.String())
+	entry, err := c.load(path)
+	if err != nil {
+		log.Printf("Ignoring unreadable cache entry for %s: %v\n", req.URL, err)
+	}
+
+	if entry != nil && (c.config.DevReplay || c.fresh(req, entry)) {
+		c.report(req, CacheHit)
+		return entry.response(req), nil
+	}
+
+	conditional := req
+	if entry != nil {
+		conditional = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			conditional.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			conditional.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := c.next.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		for _, name := range []string{"ETag", "Last-Modified", "Cache-Control", "Expires"} {
+			if value := resp.Header.Get(name); value != "" {
+				entry.Header.Set(name, value)
+			}
+		}
+		c.store(path, entry)
+		c.report(req, CacheRevalidated)
+		return entry.response(req), nil
+	}
+
+	c.report(req, CacheMiss)
+	if resp.StatusCode != http.StatusOK || strings.Contains(resp.Header.Get("Cache-Control"), "no-store") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.store(path, &cacheEntry{
+		URL:      req.URL.String(),
+		Status:   resp.StatusCode,
+		Header:   resp.Header.Clone(),
+		Body:     body,
+		StoredAt: time.Now(),
+	})
+	return resp, nil
+}
+
+func (c *Cache) fresh(req *http.Request, entry *cacheEntry) bool {
+	if c.Revalidate != nil && c.Revalidate(req) {
+		return false
+	}
+	return time.Since(entry.StoredAt) < c.config.MaxAge.Duration()
+}
+
+func (c *Cache) report(req *http.Request, result string) {
+	if c.OnResult != nil {
+		c.OnResult(req, result)
+	}
+}
+
+func (c *Cache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.config.Dir, key[:2], key+".json")
+}
+
+func (c *Cache) load(path string) (*cacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// store writes entry atomically; failures only cost a future cache miss.
+func (c *Cache) store(path string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	if err == nil {
+		err = writeFileAtomic(path, data)
+	}
+	if err != nil {
+		log.Printf("Error caching %s: %v\n", entry.URL, err)
+	}
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,158 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rent-watcher/internal/config"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// versionedServer serves the current version of a page with an ETag and a
+// Last-Modified date, answering 304 to conditional requests for it.
+type versionedServer struct {
+	mu          sync.Mutex
+	version     int
+	noStore     bool
+	conditional []string
+}
+
+func (s *versionedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	etag := fmt.Sprintf(`"v%d"`, s.version)
+	modified := time.Date(2024, 1, s.version, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	s.conditional = append(s.conditional, r.Header.Get("If-None-Match")+" "+r.Header.Get("If-Modified-Since"))
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified)
+	if s.noStore {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	fmt.Fprintf(w, "%s version %d", r.URL.Path, s.version)
+}
+
+// requests returns the conditional headers of the requests seen since the
+// last call, one "If-None-Match If-Modified-Since" string per request.
+func (s *versionedServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.conditional
+	s.conditional = nil
+	return requests
+}
+
+func (s *versionedServer) setVersion(version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+func newCacheTest(t *testing.T, cache config.CacheConfig) (*versionedServer, *httptest.Server, *Cache, *[]string) {
+	t.Helper()
+	site := &versionedServer{version: 1}
+	srv := httptest.NewServer(site)
+	t.Cleanup(srv.Close)
+
+	cache.Dir = t.TempDir()
+	c := NewCache(nil, cache)
+	var results []string
+	c.OnResult = func(_ *http.Request, result string) { results = append(results, result) }
+	return site, srv, c, &results
+}
+
+func TestCacheServesFreshEntries(t *testing.T) {
+	site, srv, cache, results := newCacheTest(t, config.CacheConfig{MaxAge: config.Duration(time.Hour)})
+	client := &http.Client{Transport: cache}
+
+	for i := 0; i < 2; i++ {
+		if got := get(t, client, srv.URL+"/detalhes/1"); got != "200 hit= /detalhes/1 version 1" {
+			t.Errorf("request %d = %q", i, got)
+		}
+	}
+	if got := site.requests(); len(got) != 1 {
+		t.Errorf("server saw %d requests, want only the first", len(got))
+	}
+	if fmt.Sprint(*results) != "[miss hit]" {
+		t.Errorf("results = %v, want [miss hit]", *results)
+	}
+}
+
+func TestCacheRevalidatesStaleEntries(t *testing.T) {
+	site, srv, cache, results := newCacheTest(t, config.CacheConfig{})
+	client := &http.Client{Transport: cache}
+
+	get(t, client, srv.URL+"/detalhes/1")
+	if got := get(t, client, srv.URL+"/detalhes/1"); got != "200 hit= /detalhes/1 version 1" {
+		t.Errorf("revalidated response = %q, want the cached page with its status", got)
+	}
+	requests := site.requests()
+	wantConditional := `"v1" ` + time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+	if len(requests) != 2 || strings.TrimSpace(requests[0]) != "" || requests[1] != wantConditional {
+		t.Errorf("conditional headers = %q, want none and then %q", requests, wantConditional)
+	}
+
+	// A changed page replaces the entry, whose validators are used next.
+	site.setVersion(2)
+	if got := get(t, client, srv.URL+"/detalhes/1"); got != "200 hit= /detalhes/1 version 2" {
+		t.Errorf("response after change = %q, want version 2", got)
+	}
+	get(t, client, srv.URL+"/detalhes/1")
+	if requests := site.requests(); len(requests) != 2 || !strings.HasPrefix(requests[1], `"v2" `) {
+		t.Errorf("conditional headers after change = %q, want the new ETag", requests)
+	}
+	if fmt.Sprint(*results) != "[miss revalidated miss revalidated]" {
+		t.Errorf("results = %v", *results)
+	}
+}
+
+func TestCacheRevalidatesListingPages(t *testing.T) {
+	site, srv, cache, results := newCacheTest(t, config.CacheConfig{MaxAge: config.Duration(time.Hour)})
+	cache.Revalidate = func(req *http.Request) bool { return strings.HasPrefix(req.URL.Path, "/listagem") }
+	client := &http.Client{Transport: cache}
+
+	for _, path := range []string{"/listagem/", "/detalhes/1", "/listagem/", "/detalhes/1"} {
+		get(t, client, srv.URL+path)
+	}
+	if got := len(site.requests()); got != 3 {
+		t.Errorf("server saw %d requests, want the listing twice and the details once", got)
+	}
+	if fmt.Sprint(*results) != "[miss miss revalidated hit]" {
+		t.Errorf("results = %v, want [miss miss revalidated hit]", *results)
+	}
+}
+
+func TestCacheSkipsUncacheableResponses(t *testing.T) {
+	site, srv, cache, _ := newCacheTest(t, config.CacheConfig{MaxAge: config.Duration(time.Hour)})
+	client := &http.Client{Transport: cache}
+
+	site.noStore = true
+	get(t, client, srv.URL+"/privado")
+	get(t, client, srv.URL+"/privado")
+	post(t, client, srv.URL+"/busca", "{}")
+	post(t, client, srv.URL+"/busca", "{}")
+	if got := len(site.requests()); got != 4 {
+		t.Errorf("server saw %d requests, want every no-store and POST request", got)
+	}
+}
+
+func TestCacheDevReplay(t *testing.T) {
+	site, srv, cache, results := newCacheTest(t, config.CacheConfig{DevReplay: true})
+	client := &http.Client{Transport: cache}
+
+	get(t, client, srv.URL+"/detalhes/1")
+	site.setVersion(2)
+	if got := get(t, client, srv.URL+"/detalhes/1"); got != "200 hit= /detalhes/1 version 1" {
+		t.Errorf("replayed response = %q, want the cached version", got)
+	}
+	if got := len(site.requests()); got != 1 || fmt.Sprint(*results) != "[miss hit]" {
+		t.Errorf("server saw %d requests with results %v, want only the first", got, *results)
+	}
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Bind ties every request to the context returned by Current, on top of the
+// request's own context. colly sends its requests without a context, so this
+// is what lets a cancelled run abort its in-flight visits, the waits of the
+// politeness and retry layers included. Current may return nil when no run is
+// in progress.
+type Bind struct {
+	next    http.RoundTripper
+	Current func() context.Context
+}
+
+func NewBind(next http.RoundTripper, current func() context.Context) *Bind {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Bind{next: next, Current: current}
+}
+
+func (b *Bind) RoundTrip(req *http.Request) (*http.Response, error) {
+	run := b.Current()
+	if run == nil || run == req.Context() {
+		return b.next.RoundTrip(req)
+	}
+	if err := run.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(run, cancel)
+	release := func() {
+		stop()
+		cancel()
+	}
+
+	resp, err := b.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		if runErr := run.Err(); runErr != nil {
+			return nil, runErr
+		}
+		return nil, err
+	}
+	// The body is still read after RoundTrip returns, so the bound context
+	// lives until it is closed.
+	resp.Body = &boundBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type boundBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *boundBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
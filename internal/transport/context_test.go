@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rent-watcher/internal/config"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripAsync sends req through rt on its own goroutine.
+func roundTripAsync(rt http.RoundTripper, req *http.Request) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	return done
+}
+
+func waitCancelled(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("request ended with %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request still running after the run was cancelled")
+	}
+}
+
+func TestBindCancelsInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	run, cancel := context.WithCancel(context.Background())
+	bind := NewBind(nil, func() context.Context { return run })
+
+	// The request has no context of its own, as colly sends them.
+	done := roundTripAsync(bind, mustRequest(t, http.MethodGet, srv.URL, ""))
+	<-started
+	cancel()
+	waitCancelled(t, done)
+}
+
+func TestBindCancelsBodyReads(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first chunk"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	run, cancel := context.WithCancel(context.Background())
+	bind := NewBind(nil, func() context.Context { return run })
+	resp, err := bind.RoundTrip(mustRequest(t, http.MethodGet, srv.URL, ""))
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	defer resp.Body.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(resp.Body)
+		done <- err
+	}()
+	cancel()
+	waitCancelled(t, done)
+}
+
+func TestBindCancelsPoliteWaits(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer srv.Close()
+
+	run, cancel := context.WithCancel(context.Background())
+	polite := NewPolite(nil, config.PolitenessConfig{Delay: config.Duration(time.Hour)}, "rent-watcher-test")
+	bind := NewBind(polite, func() context.Context { return run })
+
+	if err := <-roundTripAsync(bind, mustRequest(t, http.MethodGet, srv.URL, "")); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	// The second request waits an hour for its turn, until the run ends.
+	done := roundTripAsync(bind, mustRequest(t, http.MethodGet, srv.URL, ""))
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	waitCancelled(t, done)
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("server saw %d requests, want only the first", n)
+	}
+
+	// Once the run is over, requests fail before reaching the transport.
+	if err := <-roundTripAsync(bind, mustRequest(t, http.MethodGet, srv.URL, "")); !errors.Is(err, context.Canceled) {
+		t.Errorf("request after the run = %v, want context.Canceled", err)
+	}
+}
+
+func TestBindWithoutRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	bind := NewBind(nil, func() context.Context { return nil })
+	if got := get(t, &http.Client{Transport: bind}, srv.URL); got != "200 hit= ok" {
+		t.Errorf("response without a run = %q", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package transport provides the http.RoundTripper layers shared by every
+// scraper, whether it uses colly or a plain http.Client.
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"rent-watcher/internal/config"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// ErrDisallowedByRobots is returned for requests robots.txt does not allow.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// Polite enforces a source's politeness settings per host: a maximum number
+// of requests in flight, a minimum interval between request starts (the
+// largest of the fixed delay, the requests-per-minute budget and the
+// crawl-delay) plus random jitter, and optionally robots.txt rules.
+type Polite struct {
+	next      http.RoundTripper
+	config    config.PolitenessConfig
+	userAgent string
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+type hostLimiter struct {
+	slots chan struct{}
+
+	mu   sync.Mutex
+	next time.Time
+
+	robotsMu     sync.Mutex
+	robotsLoaded bool
+	robots       *robotstxt.Group
+}
+
+// NewPolite wraps next with the given politeness settings. userAgent is the
+// agent matched against robots.txt groups.
+func NewPolite(next http.RoundTripper, politeness config.PolitenessConfig, userAgent string) *Polite {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Polite{
+		next:      next,
+		config:    politeness,
+		userAgent: userAgent,
+		hosts:     make(map[string]*hostLimiter),
+	}
+}
+
+func (p *Polite) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := p.host(req.URL.Host)
+
+	var robots *robotstxt.Group
+	if p.config.RespectRobotsTxt && req.URL.Path != "/robots.txt" {
+		robots = host.loadRobots(p, req)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if robots != nil && !robots.Test(req.URL.RequestURI()) {
+			return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, req.URL)
+		}
+	}
+
+	if host.slots != nil {
+		select {
+		case host.slots <- struct{}{}:
+			defer func() { <-host.slots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err := sleep(ctx, time.Until(host.reserve(p.interval(robots), p.config.RandomDelay.Duration()))); err != nil {
+		return nil, err
+	}
+
+	return p.next.RoundTrip(req)
+}
+
+func (p *Polite) host(name string) *hostLimiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	host, ok := p.hosts[name]
+	if !ok {
+		host = &hostLimiter{}
+		if p.config.Parallelism > 0 {
+			host.slots = make(chan struct{}, p.config.Parallelism)
+		}
+		p.hosts[name] = host
+	}
+	return host
+}
+
+// interval returns the minimum time between two request starts on a host
+// whose robots.txt group is robots.
+func (p *Polite) interval(robots *robotstxt.Group) time.Duration {
+	interval := p.config.Delay.Duration()
+	if p.config.RequestsPerMinute > 0 {
+		interval = max(interval, time.Minute/time.Duration(p.config.RequestsPerMinute))
+	}
+
+	crawlDelay := p.config.CrawlDelay.Duration()
+	if crawlDelay <= 0 && robots != nil {
+		crawlDelay = robots.CrawlDelay
+	}
+	return max(interval, crawlDelay)
+}
+
+// reserve books the next request slot on the host and returns when the
+// request may start.
+func (hl *hostLimiter) reserve(interval, randomDelay time.Duration) time.Time {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	start := time.Now()
+	if hl.next.After(start) {
+		start = hl.next
+	}
+	if randomDelay > 0 {
+		start = start.Add(time.Duration(rand.Int63n(int64(randomDelay))))
+	}
+	hl.next = start.Add(interval)
+	return start
+}
+
+// loadRobots returns the robots.txt group of host, fetching it on the first
+// request. The fetch uses req's context; a fetch that fails or is cancelled
+// is not cached and is tried again on the next request.
+func (hl *hostLimiter) loadRobots(p *Polite, req *http.Request) *robotstxt.Group {
+	hl.robotsMu.Lock()
+	defer hl.robotsMu.Unlock()
+	if !hl.robotsLoaded {
+		hl.robots, hl.robotsLoaded = p.fetchRobots(req.Context(), req)
+	}
+	return hl.robots
+}
+
+// fetchRobots loads robots.txt for the host of req and reports whether the
+// server answered. A robots.txt that cannot be fetched allows everything;
+// robotstxt maps server errors to disallow all.
+func (p *Polite) fetchRobots(ctx context.Context, req *http.Request) (*robotstxt.Group, bool) {
+	robotsURL := *req.URL
+	robotsURL.Path = "/robots.txt"
+	robotsURL.RawPath = ""
+	robotsURL.RawQuery = ""
+	robotsURL.Fragment = ""
+
+	robotsReq, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, true
+	}
+	robotsReq.Header.Set("User-Agent", p.userAgent)
+
+	resp, err := p.next.RoundTrip(robotsReq)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	robots, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return nil, ctx.Err() == nil
+	}
+	return robots.FindGroup(p.userAgent), true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
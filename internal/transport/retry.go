@@ -0,0 +1,175 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"rent-watcher/internal/config"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+	defaultRetryBudget    = 20
+	defaultMaxRetryAfter  = 5 * time.Minute
+)
+
+var defaultRetryStatuses = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Retry retries requests that failed with a network error or a retryable
+// status, with exponential backoff and jitter. A Retry-After header replaces
+// the computed backoff, capped at MaxRetryAfter. Every retry consumes one unit of a budget shared by
+// all requests until Reset is called, so a broken site cannot stall a run.
+type Retry struct {
+	next    http.RoundTripper
+	config  config.RetryConfig
+	OnRetry func(req *http.Request, attempt int, reason string)
+
+	mu     sync.Mutex
+	budget int
+}
+
+// NewRetry wraps next with the given retry settings.
+func NewRetry(next http.RoundTripper, retry config.RetryConfig) *Retry {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if retry.MaxAttempts <= 0 {
+		retry.MaxAttempts = defaultMaxAttempts
+	}
+	if retry.InitialBackoff <= 0 {
+		retry.InitialBackoff = config.Duration(defaultInitialBackoff)
+	}
+	if retry.MaxBackoff <= 0 {
+		retry.MaxBackoff = config.Duration(defaultMaxBackoff)
+	}
+	if retry.MaxRetryAfter <= 0 {
+		retry.MaxRetryAfter = config.Duration(defaultMaxRetryAfter)
+	}
+	if retry.Budget == 0 {
+		retry.Budget = defaultRetryBudget
+	}
+	if len(retry.Statuses) == 0 {
+		retry.Statuses = defaultRetryStatuses
+	}
+
+	return &Retry{next: next, config: retry, budget: retry.Budget}
+}
+
+// Reset restores the retry budget, typically at the start of a run.
+func (r *Retry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.budget = r.config.Budget
+}
+
+func (r *Retry) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := r.next.RoundTrip(req)
+
+		reason, wait, retryable := r.retryable(req.Context(), resp, err, attempt)
+		if !retryable || attempt >= r.config.MaxAttempts || !r.canReplay(req) || !r.take() {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if r.OnRetry != nil {
+			r.OnRetry(req, attempt, reason)
+		}
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether a result should be retried, why, and after how
+// long.
+func (r *Retry) retryable(ctx context.Context, resp *http.Response, err error, attempt int) (string, time.Duration, bool) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrDisallowedByRobots) || errors.Is(err, ErrNoProxyAvailable) || errors.Is(err, ErrNotArchived) {
+			return "", 0, false
+		}
+		return err.Error(), r.backoff(attempt), true
+	}
+
+	if !slices.Contains(r.config.Statuses, resp.StatusCode) {
+		return "", 0, false
+	}
+	wait := r.backoff(attempt)
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		wait = min(retryAfter, r.config.MaxRetryAfter.Duration())
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		// The run would end before the retry; report the failure instead.
+		return "", 0, false
+	}
+	return resp.Status, wait, true
+}
+
+// backoff returns the exponential backoff for attempt with jitter in
+// [backoff/2, backoff].
+func (r *Retry) backoff(attempt int) time.Duration {
+	backoff := r.config.InitialBackoff.Duration() << (attempt - 1)
+	if backoff <= 0 || backoff > r.config.MaxBackoff.Duration() {
+		backoff = r.config.MaxBackoff.Duration()
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (r *Retry) canReplay(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// take consumes one retry from the budget. A negative budget is unlimited.
+func (r *Retry) take() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.config.Budget < 0 {
+		return true
+	}
+	if r.budget <= 0 {
+		return false
+	}
+	r.budget--
+	return true
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rent-watcher/internal/config"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer answers the first failures requests with status and the
+// given Retry-After, then with 200 and the request body.
+func flakyServer(t *testing.T, failures int32, status int, retryAfter string) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	srv, hits := flakyServer(t, 1, http.StatusServiceUnavailable, "1")
+	retry := NewRetry(nil, config.RetryConfig{InitialBackoff: config.Duration(time.Hour), MaxBackoff: config.Duration(time.Hour)})
+	var reasons []string
+	retry.OnRetry = func(_ *http.Request, _ int, reason string) { reasons = append(reasons, reason) }
+
+	start := time.Now()
+	if got := do(t, &http.Client{Transport: retry}, mustRequest(t, http.MethodPost, srv.URL, "payload")); got != "200 hit= payload" {
+		t.Errorf("response = %q, want the retried POST to succeed with its body", got)
+	}
+	// The one second of Retry-After replaces the hour of backoff.
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 10*time.Second {
+		t.Errorf("retried after %s, want about 1s", elapsed)
+	}
+	if atomic.LoadInt32(hits) != 2 || len(reasons) != 1 || reasons[0] != "503 Service Unavailable" {
+		t.Errorf("hits = %d, retries = %v; want one retry of the 503", atomic.LoadInt32(hits), reasons)
+	}
+}
+
+func TestRetryCapsRetryAfter(t *testing.T) {
+	srv, hits := flakyServer(t, 1, http.StatusTooManyRequests, "3600")
+	retry := NewRetry(nil, config.RetryConfig{MaxRetryAfter: config.Duration(50 * time.Millisecond)})
+
+	start := time.Now()
+	if got := get(t, &http.Client{Transport: retry}, srv.URL); got != "200 hit= " {
+		t.Errorf("response = %q, want 200", got)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("retried after %s, want max_retry_after to cap the hour of Retry-After", elapsed)
+	}
+	if atomic.LoadInt32(hits) != 2 {
+		t.Errorf("hits = %d, want 2", atomic.LoadInt32(hits))
+	}
+}
+
+func TestRetryGivesUpBeforeDeadline(t *testing.T) {
+	srv, hits := flakyServer(t, 1, http.StatusServiceUnavailable, "60")
+	retry := NewRetry(nil, config.RetryConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := mustRequest(t, http.MethodGet, srv.URL, "").WithContext(ctx)
+	if got := do(t, &http.Client{Transport: retry}, req); got != "503 hit= " {
+		t.Errorf("response = %q, want the 503 the run has no time to retry", got)
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Errorf("hits = %d, want 1", atomic.LoadInt32(hits))
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	srv, hits := flakyServer(t, 1000, http.StatusInternalServerError, "")
+	retry := NewRetry(nil, config.RetryConfig{
+		InitialBackoff: config.Duration(time.Millisecond),
+		MaxBackoff:     config.Duration(time.Millisecond),
+		Budget:         2,
+	})
+	client := &http.Client{Transport: retry}
+
+	for i, want := range []int32{3, 4, 5} {
+		if got := get(t, client, srv.URL); got != "500 hit= " {
+			t.Errorf("request %d = %q, want 500", i, got)
+		}
+		if got := atomic.LoadInt32(hits); got != want {
+			t.Errorf("after request %d the server saw %d requests, want %d", i, got, want)
+		}
+	}
+
+	// A new run gets its budget back.
+	retry.Reset()
+	get(t, client, srv.URL)
+	if got := atomic.LoadInt32(hits); got != 8 {
+		t.Errorf("after Reset the server saw %d requests, want 8", got)
+	}
+
+	// Statuses that are not retryable are returned at once.
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	var retried bool
+	retry.OnRetry = func(*http.Request, int, string) { retried = true }
+	if got := get(t, client, notFound.URL); got[:3] != "404" || retried {
+		t.Errorf("404 response = %q, retried = %v; want no retry", got, retried)
+	}
+}
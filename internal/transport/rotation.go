@@ -0,0 +1,193 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"rent-watcher/internal/config"
+	"sync"
+	"time"
+)
+
+const (
+	RotatePerRequest = "request"
+	RotatePerSession = "session"
+
+	defaultMaxProxyFailures = 3
+	defaultBenchDuration    = 10 * time.Minute
+)
+
+// ErrNoProxyAvailable is returned when every configured proxy is benched.
+var ErrNoProxyAvailable = errors.New("no proxy available")
+
+// benchStatuses are responses that suggest a proxy has been blocked.
+var benchStatuses = map[int]bool{
+	http.StatusForbidden:                  true,
+	http.StatusProxyAuthRequired:          true,
+	http.StatusTooManyRequests:            true,
+	http.StatusBadGateway:                 true,
+	http.StatusServiceUnavailable:         true,
+	http.StatusGatewayTimeout:             true,
+	http.StatusUnavailableForLegalReasons: true,
+}
+
+// Rotation spreads requests over a pool of user agents and HTTP or SOCKS5
+// proxies, choosing new ones for every request or once per session. A proxy
+// that fails MaxFailures times in a row is benched for BenchDuration.
+type Rotation struct {
+	next       http.RoundTripper
+	userAgents []string
+	perSession bool
+	maxFails   int
+	bench      time.Duration
+
+	mu        sync.Mutex
+	proxies   []*proxy
+	cursor    int
+	sessionUA string
+	session   *proxy
+}
+
+type proxy struct {
+	url       *url.URL
+	transport http.RoundTripper
+	failures  int
+	benched   time.Time
+}
+
+// NewRotation wraps next with the given rotation settings. Requests go
+// through next directly when no proxies are configured. Invalid proxy URLs
+// are logged and skipped.
+func NewRotation(next http.RoundTripper, rotation config.RotationConfig) *Rotation {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Rotation{
+		next:       next,
+		userAgents: rotation.UserAgents,
+		perSession: rotation.Mode == RotatePerSession,
+		maxFails:   rotation.MaxFailures,
+		bench:      rotation.BenchDuration.Duration(),
+	}
+	if r.maxFails <= 0 {
+		r.maxFails = defaultMaxProxyFailures
+	}
+	if r.bench <= 0 {
+		r.bench = defaultBenchDuration
+	}
+
+	for _, raw := range rotation.Proxies {
+		proxyURL, err := url.Parse(raw)
+		if err != nil || proxyURL.Host == "" {
+			log.Printf("Ignoring invalid proxy %q: %v\n", raw, err)
+			continue
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			log.Printf("Ignoring proxy %q: unsupported scheme %q\n", raw, proxyURL.Scheme)
+			continue
+		}
+
+		base, ok := next.(*http.Transport)
+		if !ok {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		proxyTransport := base.Clone()
+		proxyTransport.Proxy = http.ProxyURL(proxyURL)
+		r.proxies = append(r.proxies, &proxy{url: proxyURL, transport: proxyTransport})
+	}
+
+	r.Reset()
+	return r
+}
+
+// Reset starts a new session: per-session rotation picks a new user agent
+// and proxy for the following requests.
+func (r *Rotation) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessionUA = r.pickUserAgent()
+	r.session = nil
+}
+
+func (r *Rotation) RoundTrip(req *http.Request) (*http.Response, error) {
+	userAgent, p, err := r.pick()
+	if err != nil {
+		return nil, err
+	}
+
+	if userAgent != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent)
+	}
+	if p == nil {
+		return r.next.RoundTrip(req)
+	}
+
+	resp, err := p.transport.RoundTrip(req)
+	r.report(p, err != nil || benchStatuses[resp.StatusCode])
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", p.url.Host, err)
+	}
+	return resp, nil
+}
+
+func (r *Rotation) pick() (string, *proxy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userAgent := r.sessionUA
+	if !r.perSession {
+		userAgent = r.pickUserAgent()
+	}
+	if len(r.proxies) == 0 {
+		return userAgent, nil, nil
+	}
+
+	if r.perSession && r.session != nil && !r.isBenched(r.session) {
+		return userAgent, r.session, nil
+	}
+	for range r.proxies {
+		p := r.proxies[r.cursor]
+		r.cursor = (r.cursor + 1) % len(r.proxies)
+		if !r.isBenched(p) {
+			r.session = p
+			return userAgent, p, nil
+		}
+	}
+	return "", nil, ErrNoProxyAvailable
+}
+
+func (r *Rotation) pickUserAgent() string {
+	if len(r.userAgents) == 0 {
+		return ""
+	}
+	return r.userAgents[rand.Intn(len(r.userAgents))]
+}
+
+func (r *Rotation) isBenched(p *proxy) bool {
+	return time.Now().Before(p.benched)
+}
+
+// report records the outcome of a request through p, benching it after
+// maxFails consecutive failures.
+func (r *Rotation) report(p *proxy, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !failed {
+		p.failures = 0
+		return
+	}
+	p.failures++
+	if p.failures >= r.maxFails {
+		p.failures = 0
+		p.benched = time.Now().Add(r.bench)
+		log.Printf("Benching proxy %s for %s\n", p.url.Host, r.bench)
+	}
+}
@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rent-watcher/internal/config"
+	"testing"
+	"time"
+)
+
+// fakeProxy answers every proxied request itself with status, naming itself
+// and the user agent it saw.
+func fakeProxy(t *testing.T, name string, status int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Hit", name)
+		w.WriteHeader(status)
+		fmt.Fprint(w, r.UserAgent())
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// via returns the proxy that answered a request, or the error.
+func via(t *testing.T, rotation *Rotation) string {
+	t.Helper()
+	resp, err := rotation.RoundTrip(mustRequest(t, http.MethodGet, "http://imoveis.example/listagem", ""))
+	if err != nil {
+		return err.Error()
+	}
+	resp.Body.Close()
+	return resp.Header.Get("X-Hit")
+}
+
+func TestRotationPerRequest(t *testing.T) {
+	rotation := NewRotation(nil, config.RotationConfig{
+		Proxies: []string{fakeProxy(t, "a", http.StatusOK), "ftp://ignored:21", fakeProxy(t, "b", http.StatusOK)},
+	})
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, via(t, rotation))
+	}
+	if fmt.Sprint(got) != "[a b a b]" {
+		t.Errorf("requests went through %v, want alternating proxies", got)
+	}
+}
+
+func TestRotationBenchesFailingProxy(t *testing.T) {
+	rotation := NewRotation(nil, config.RotationConfig{
+		Proxies:       []string{fakeProxy(t, "blocked", http.StatusForbidden), fakeProxy(t, "ok", http.StatusOK)},
+		MaxFailures:   2,
+		BenchDuration: config.Duration(time.Hour),
+	})
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, via(t, rotation))
+	}
+	// The blocked proxy is benched after its second 403.
+	if fmt.Sprint(got) != "[blocked ok blocked ok ok ok]" {
+		t.Errorf("requests went through %v", got)
+	}
+
+	only := NewRotation(nil, config.RotationConfig{
+		Proxies:     []string{fakeProxy(t, "blocked", http.StatusTooManyRequests)},
+		MaxFailures: 1,
+	})
+	via(t, only)
+	if _, err := only.RoundTrip(mustRequest(t, http.MethodGet, "http://imoveis.example/", "")); !errors.Is(err, ErrNoProxyAvailable) {
+		t.Errorf("request with every proxy benched: err = %v, want ErrNoProxyAvailable", err)
+	}
+}
+
+func TestRotationPerSession(t *testing.T) {
+	userAgents := []string{"agent-1", "agent-2", "agent-3", "agent-4"}
+	rotation := NewRotation(nil, config.RotationConfig{
+		Proxies:    []string{fakeProxy(t, "a", http.StatusOK), fakeProxy(t, "b", http.StatusOK)},
+		UserAgents: userAgents,
+		Mode:       RotatePerSession,
+	})
+	client := &http.Client{Transport: rotation}
+
+	session := get(t, client, "http://imoveis.example/1")
+	for i := 0; i < 3; i++ {
+		if got := get(t, client, "http://imoveis.example/2"); got != session {
+			t.Errorf("request %d = %q, want the session's proxy and user agent %q", i, got, session)
+		}
+	}
+
+	rotation.Reset()
+	if got := get(t, client, "http://imoveis.example/1"); got[:len("200 hit=b")] != "200 hit=b" {
+		t.Errorf("first request after Reset = %q, want the next proxy", got)
+	}
+}
+
+func TestRotationUserAgentsWithoutProxies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.UserAgent())
+	}))
+	defer srv.Close()
+
+	userAgents := map[string]bool{"agent-1": true, "agent-2": true}
+	client := &http.Client{Transport: NewRotation(nil, config.RotationConfig{UserAgents: []string{"agent-1", "agent-2"}})}
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		ua := get(t, client, srv.URL)[len("200 hit= "):]
+		if !userAgents[ua] {
+			t.Fatalf("request sent user agent %q", ua)
+		}
+		seen[ua] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("50 requests used user agents %v, want both", seen)
+	}
+}
@@ -0,0 +1,372 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"rent-watcher/internal/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLoginFailed is returned when the login form of a session is rejected.
+var ErrLoginFailed = errors.New("login failed")
+
+// maxExpiryCheckBody caps how much of a response is searched for ExpiredText
+// or a login form.
+const maxExpiryCheckBody = 2 << 20
+
+// passwordInputRegex finds the password field of a login form.
+var passwordInputRegex = regexp.MustCompile(`(?i)<input[^>]+type\s*=\s*["']?password`)
+
+// Session keeps the cookies of a source in a jar, optionally persisted to a
+// file, and logs in with the configured form before the first request and
+// whenever a response shows the session expired. The request that hit the
+// expired session is sent again once after logging in.
+type Session struct {
+	next   http.RoundTripper
+	config config.SessionConfig
+	jar    *cookiejar.Jar
+
+	mu sync.Mutex
+	// cookies mirrors the jar with every attribute of the cookies, keyed by
+	// cookieKey, since the jar only hands back names and values.
+	cookies map[string]persistedCookie
+	login   sync.Mutex
+	// generation counts logins, so requests that see the session expire
+	// together only log in once.
+	generation int
+}
+
+// persistedCookie is a cookie as stored in the cookie file, with the URL of
+// the response that set it.
+type persistedCookie struct {
+	URL      string     `json:"url"`
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HttpOnly bool       `json:"http_only,omitempty"`
+}
+
+func (pc persistedCookie) cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     pc.Name,
+		Value:    pc.Value,
+		Path:     pc.Path,
+		Domain:   pc.Domain,
+		Secure:   pc.Secure,
+		HttpOnly: pc.HttpOnly,
+	}
+	if pc.Expires != nil {
+		cookie.Expires = *pc.Expires
+	}
+	return cookie
+}
+
+func (pc persistedCookie) expired(now time.Time) bool {
+	return pc.Expires != nil && !pc.Expires.After(now)
+}
+
+// cookieKey identifies a cookie the way a jar does: by domain, path and name.
+// Cookies without a Domain attribute belong to the host that set them.
+func cookieKey(u *url.URL, c *http.Cookie) string {
+	domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+	if domain == "" {
+		domain = u.Hostname()
+	}
+	return domain + ";" + c.Path + ";" + c.Name
+}
+
+// NewSession wraps next with the session described by session, loading the
+// cookie file if it exists.
+func NewSession(next http.RoundTripper, session config.SessionConfig) (*Session, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+
+	s := &Session{next: next, config: session, jar: jar, cookies: make(map[string]persistedCookie)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Session) RoundTrip(req *http.Request) (*http.Response, error) {
+	generation, err := s.ensureLogin(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.send(req)
+	if err != nil || s.config.LoginURL == "" || !s.expired(resp) {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body was consumed and cannot be sent again.
+		return resp, nil
+	}
+
+	log.Printf("Session expired on %s, logging in again\n", req.URL)
+	resp.Body.Close()
+	if err := s.relogin(req, generation); err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return s.send(retry)
+}
+
+// send adds the jar's cookies to req and stores those the response sets.
+func (s *Session) send(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	for _, cookie := range s.jar.Cookies(req.URL) {
+		out.AddCookie(cookie)
+	}
+
+	resp, err := s.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		s.setCookies(req.URL, cookies)
+	}
+	return resp, nil
+}
+
+// expired reports whether resp shows the session is no longer logged in:
+// a 401, a redirect to ExpiredURL or to the login page, or a body containing
+// ExpiredText. Without ExpiredText, an HTML page with a password field is
+// taken as the login form.
+func (s *Session) expired(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		if s.config.ExpiredURL != "" && strings.Contains(location, s.config.ExpiredURL) {
+			return true
+		}
+		if s.isLoginURL(resp.Request, location) {
+			return true
+		}
+	}
+
+	if s.config.ExpiredText != "" {
+		body, err := peekBody(resp)
+		return err == nil && bytes.Contains(body, []byte(s.config.ExpiredText))
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return false
+	}
+	body, err := peekBody(resp)
+	return err == nil && passwordInputRegex.Match(body)
+}
+
+// peekBody reads the start of resp's body and puts it back.
+func peekBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxExpiryCheckBody))
+	rest := resp.Body
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+	return body, err
+}
+
+// isLoginURL reports whether location, relative to req, is the login page.
+func (s *Session) isLoginURL(req *http.Request, location string) bool {
+	login, err := url.Parse(s.config.LoginURL)
+	if err != nil || req == nil {
+		return false
+	}
+	target, err := req.URL.Parse(location)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(target.Host, login.Host) && strings.TrimSuffix(target.Path, "/") == strings.TrimSuffix(login.Path, "/")
+}
+
+// ensureLogin logs in before the first request of a session that has a login
+// form, and returns the login generation the request runs under.
+func (s *Session) ensureLogin(req *http.Request) (int, error) {
+	if s.config.LoginURL == "" {
+		return 0, nil
+	}
+	s.login.Lock()
+	defer s.login.Unlock()
+	if s.generation == 0 && !s.hasCookies() {
+		if err := s.doLogin(req); err != nil {
+			return 0, err
+		}
+	}
+	return s.generation, nil
+}
+
+// relogin logs in again unless another request already did since generation.
+func (s *Session) relogin(req *http.Request, generation int) error {
+	s.login.Lock()
+	defer s.login.Unlock()
+	if s.generation != generation {
+		return nil
+	}
+	return s.doLogin(req)
+}
+
+func (s *Session) doLogin(req *http.Request) error {
+	form := url.Values{}
+	for name, value := range s.config.LoginFields {
+		form.Set(name, os.ExpandEnv(value))
+	}
+	form.Set(fieldOrDefault(s.config.UsernameField, "username"), os.ExpandEnv(s.config.Username))
+	form.Set(fieldOrDefault(s.config.PasswordField, "password"), os.ExpandEnv(s.config.Password))
+
+	login, err := http.NewRequestWithContext(req.Context(), http.MethodPost, s.config.LoginURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create login request: %w", err)
+	}
+	login.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if ua := req.Header.Get("User-Agent"); ua != "" {
+		login.Header.Set("User-Agent", ua)
+	}
+
+	resp, err := s.send(login)
+	if err != nil {
+		return fmt.Errorf("failed to log in to %s: %w", s.config.LoginURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest || s.expired(resp) {
+		return fmt.Errorf("%w at %s: %s", ErrLoginFailed, s.config.LoginURL, resp.Status)
+	}
+
+	s.generation++
+	log.Printf("Logged in to %s\n", login.URL.Host)
+	return nil
+}
+
+func fieldOrDefault(field, fallback string) string {
+	if field == "" {
+		return fallback
+	}
+	return field
+}
+
+func (s *Session) hasCookies() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, c := range s.cookies {
+		if !c.expired(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Session) setCookies(u *url.URL, cookies []*http.Cookie) {
+	s.jar.SetCookies(u, cookies)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		stored := persistedCookie{
+			URL:      u.String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		expires := c.Expires
+		switch {
+		case c.MaxAge < 0:
+			expires = now
+		case c.MaxAge > 0:
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if !expires.IsZero() {
+			stored.Expires = &expires
+		}
+		s.cookies[cookieKey(u, c)] = stored
+	}
+	if err := s.save(); err != nil {
+		log.Printf("Error saving cookies: %v\n", err)
+	}
+}
+
+func (s *Session) load() error {
+	if s.config.CookieFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.config.CookieFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cookie file: %w", err)
+	}
+
+	var stored []persistedCookie
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to decode cookie file %s: %w", s.config.CookieFile, err)
+	}
+	now := time.Now()
+	for _, c := range stored {
+		u, err := url.Parse(c.URL)
+		if err != nil || c.expired(now) {
+			continue
+		}
+		cookie := c.cookie()
+		s.jar.SetCookies(u, []*http.Cookie{cookie})
+		s.cookies[cookieKey(u, cookie)] = c
+	}
+	return nil
+}
+
+// save writes the cookies that have not expired. Callers hold s.mu.
+func (s *Session) save() error {
+	if s.config.CookieFile == "" {
+		return nil
+	}
+
+	stored := []persistedCookie{}
+	now := time.Now()
+	for key, c := range s.cookies {
+		if c.expired(now) {
+			delete(s.cookies, key)
+			continue
+		}
+		stored = append(stored, c)
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.config.CookieFile), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.config.CookieFile, data)
+}
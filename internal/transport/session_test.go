@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"rent-watcher/internal/config"
+	"sync"
+	"testing"
+)
+
+const loginPage = `<html><body><form action="/login" method="post">
+<input type="text" name="email"><input type="password" name="senha">
+</form></body></html>`
+
+// loginSite is a site whose pages need the session cookie its login form
+// sets. Expiring the site invalidates every session handed out before.
+type loginSite struct {
+	mu      sync.Mutex
+	logins  int
+	current string
+}
+
+func (s *loginSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if r.URL.Path == "/login" {
+		if r.PostFormValue("email") != "user@example.com" || r.PostFormValue("senha") != os.Getenv("RENT_WATCHER_TEST_PASSWORD") {
+			fmt.Fprint(w, loginPage)
+			return
+		}
+		s.logins++
+		s.current = fmt.Sprintf("token-%d", s.logins)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: s.current, Path: "/", MaxAge: 3600, HttpOnly: true})
+		fmt.Fprint(w, "welcome")
+		return
+	}
+
+	if cookie, err := r.Cookie("session"); err != nil || cookie.Value != s.current {
+		// The site answers expired sessions with its login form and a 200.
+		fmt.Fprint(w, loginPage)
+		return
+	}
+	fmt.Fprintf(w, "listing %s", r.URL.Path)
+}
+
+func (s *loginSite) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = ""
+}
+
+func (s *loginSite) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func newSessionTest(t *testing.T, password string) (*loginSite, *httptest.Server, config.SessionConfig) {
+	t.Helper()
+	t.Setenv("RENT_WATCHER_TEST_PASSWORD", "s3cret")
+	site := &loginSite{}
+	srv := httptest.NewServer(site)
+	t.Cleanup(srv.Close)
+	return site, srv, config.SessionConfig{
+		CookieFile:    filepath.Join(t.TempDir(), "cookies.json"),
+		LoginURL:      srv.URL + "/login",
+		UsernameField: "email",
+		PasswordField: "senha",
+		Username:      "user@example.com",
+		Password:      password,
+	}
+}
+
+func TestSessionLogsInAgainOnLoginForm(t *testing.T) {
+	site, srv, cfg := newSessionTest(t, "$RENT_WATCHER_TEST_PASSWORD")
+	session, err := NewSession(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	client := &http.Client{Transport: session}
+
+	if got := get(t, client, srv.URL+"/imovel/1"); got != "200 hit= listing /imovel/1" {
+		t.Errorf("first response = %q, want the page after logging in", got)
+	}
+	get(t, client, srv.URL+"/imovel/2")
+	if n := site.loginCount(); n != 1 {
+		t.Errorf("logged in %d times, want once for both requests", n)
+	}
+
+	site.expire()
+	if got := get(t, client, srv.URL+"/imovel/3"); got != "200 hit= listing /imovel/3" {
+		t.Errorf("response after expiry = %q, want the page sent again after a new login", got)
+	}
+	if n := site.loginCount(); n != 2 {
+		t.Errorf("logged in %d times, want a second login after expiry", n)
+	}
+}
+
+func TestSessionPersistsCookies(t *testing.T) {
+	site, srv, cfg := newSessionTest(t, "$RENT_WATCHER_TEST_PASSWORD")
+	first, err := NewSession(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	get(t, &http.Client{Transport: first}, srv.URL+"/imovel/1")
+
+	data, err := os.ReadFile(cfg.CookieFile)
+	if err != nil {
+		t.Fatalf("failed to read cookie file: %v", err)
+	}
+	var stored []persistedCookie
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("failed to decode cookie file: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Value != "token-1" || stored[0].Path != "/" || !stored[0].HttpOnly || stored[0].Expires == nil {
+		t.Errorf("cookie file = %s, want the session cookie with its attributes", data)
+	}
+
+	// A new run reuses the stored session instead of logging in.
+	second, err := NewSession(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	if got := get(t, &http.Client{Transport: second}, srv.URL+"/imovel/2"); got != "200 hit= listing /imovel/2" {
+		t.Errorf("response with the stored session = %q", got)
+	}
+	if n := site.loginCount(); n != 1 {
+		t.Errorf("logged in %d times, want the stored session reused", n)
+	}
+}
+
+func TestSessionLoginFailure(t *testing.T) {
+	_, srv, cfg := newSessionTest(t, "wrong")
+	session, err := NewSession(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	_, err = session.RoundTrip(mustRequest(t, http.MethodGet, srv.URL+"/imovel/1", ""))
+	if !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("RoundTrip = %v, want ErrLoginFailed", err)
+	}
+}